@@ -6,8 +6,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	// Embed the timezone database for the leader-timezone flag, the runtime image has none
+	_ "time/tzdata"
 
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
 )
 
 func main() {
@@ -24,14 +27,15 @@ func main() {
 	}()
 
 	// Initialize and run the command
-	rootCmd, err := commands.InitRunCommand(ctx)
+	rootCmd, err := commands.InitRootCommand(ctx)
 	if err != nil {
-		log.Printf("init run command: %v\n", err)
+		log.Printf("init root command: %v\n", err)
 		os.Exit(1)
 	}
 	err = rootCmd.Execute()
 	if err != nil {
 		log.Printf("run command: %v\n", err)
-		os.Exit(1)
+		// The exit code tells orchestrators why the node stopped
+		os.Exit(termination.ReasonOf(err).ExitCode())
 	}
 }
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+)
+
+var (
+	_ run.Observer        = &Recorder{}
+	_ run.RestartObserver = &Recorder{}
+)
+
+// Recorder observes the state machine and keeps the counts of taken transitions
+type Recorder struct {
+	run.NopObserver
+
+	mu       sync.Mutex
+	state    string
+	since    time.Time
+	finished bool
+	restarts int
+	counts   map[run.Edge]int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{counts: make(map[run.Edge]int)}
+}
+
+// TransitionCount is the number of times a transition was taken
+type TransitionCount struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// Snapshot is the view of the recorder served by the admin server
+type Snapshot struct {
+	State       string            `json:"state"`
+	Since       time.Time         `json:"since"`
+	Finished    bool              `json:"finished"`
+	Restarts    int               `json:"restarts"`
+	Transitions []TransitionCount `json:"transitions"`
+}
+
+func (r *Recorder) OnTransition(_ context.Context, from, to string, _ run.Reason, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[run.Edge{From: from, To: to}]++
+	r.state, r.since = to, time.Now()
+	r.finished = to == run.Terminal
+}
+
+func (r *Recorder) OnRestart(context.Context, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.restarts++
+}
+
+// Snapshot returns the current state and the transition counts sorted by edge
+func (r *Recorder) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := Snapshot{
+		State:       r.state,
+		Since:       r.since,
+		Finished:    r.finished,
+		Restarts:    r.restarts,
+		Transitions: make([]TransitionCount, 0, len(r.counts)),
+	}
+	for edge, count := range r.counts {
+		snapshot.Transitions = append(snapshot.Transitions, TransitionCount{From: edge.From, To: edge.To, Count: count})
+	}
+	sort.Slice(snapshot.Transitions, func(i, j int) bool {
+		a, b := snapshot.Transitions[i], snapshot.Transitions[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return snapshot
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+)
+
+// shutdownTimeout bounds the graceful shutdown of the server
+const shutdownTimeout = 5 * time.Second
+
+// Server exposes the state of the node over HTTP:
+// GET /transitions returns the counts of taken transitions and the current state,
+// GET /healthz returns 200 while the state machine runs and 503 after it finished,
+// GET, POST and DELETE /maintenance show, enable and release the manual maintenance
+type Server struct {
+	logger      *slog.Logger
+	addr        string
+	recorder    *Recorder
+	maintenance *maintenance.Switch
+}
+
+func New(logger *slog.Logger, addr string, recorder *Recorder, maintenance *maintenance.Switch) *Server {
+	return &Server{
+		logger:      logger.With("subsystem", "AdminServer"),
+		addr:        addr,
+		recorder:    recorder,
+		maintenance: maintenance,
+	}
+}
+
+// Run serves requests until the context is done
+func (s *Server) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /transitions", s.transitions)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /maintenance", s.maintenanceStatus)
+	mux.HandleFunc("POST /maintenance", s.setMaintenance(true))
+	mux.HandleFunc("DELETE /maintenance", s.setMaintenance(false))
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Admin server listening", slog.String("addr", listener.Addr().String()))
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelError, "Error shutting down admin server", slog.String("error", err.Error()))
+		}
+	}()
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) transitions(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, s.recorder.Snapshot())
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.recorder.Snapshot()
+	status := http.StatusOK
+	if snapshot.Finished {
+		status = http.StatusServiceUnavailable
+	}
+	s.writeJSON(w, r, status, map[string]string{"state": snapshot.State})
+}
+
+func (s *Server) maintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, map[string]bool{"enabled": s.maintenance.Enabled()})
+}
+
+func (s *Server) setMaintenance(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.maintenance.Set(enabled)
+		s.logger.LogAttrs(r.Context(), slog.LevelInfo, "Maintenance changed through admin API", slog.Bool("enabled", enabled))
+		// The flag file may keep the maintenance on after a release
+		s.maintenanceStatus(w, r)
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "Error writing response", slog.String("error", err.Error()))
+	}
+}
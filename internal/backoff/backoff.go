@@ -0,0 +1,60 @@
+package backoff
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy describes exponential backoff with jitter between retry attempts
+type Policy struct {
+	// Initial is the delay before the second attempt, the first one is made immediately
+	Initial time.Duration
+	// Max caps the delay
+	Max time.Duration
+	// Multiplier grows the delay after every attempt
+	Multiplier float64
+	// Jitter randomizes the delay by up to this fraction in both directions
+	Jitter float64
+	// MaxAttempts limits the number of attempts, zero means unlimited
+	MaxAttempts int
+}
+
+// Validate checks that the policy produces sane delays
+func (p Policy) Validate() error {
+	switch {
+	case p.Initial <= 0:
+		return errors.New("initial delay must be positive")
+	case p.Max < p.Initial:
+		return errors.New("max delay must not be less than initial delay")
+	case p.Multiplier < 1:
+		return errors.New("multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return errors.New("jitter must be between 0 and 1")
+	case p.MaxAttempts < 0:
+		return errors.New("max attempts must not be negative")
+	}
+	return nil
+}
+
+// Delay returns the wait before the attempt with the zero-based index
+func (p Policy) Delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	// The base is capped before the jitter: math.Pow overflows to +Inf for large attempts,
+	// and a base above Max would leave the jitter nothing to spread
+	base := float64(p.Initial) * math.Pow(p.Multiplier, float64(attempt-1))
+	if math.IsNaN(base) || base > float64(p.Max) {
+		base = float64(p.Max)
+	}
+	// The upper bound of the jitter is capped too, so delays at the cap spread over [Max·(1−Jitter), Max]
+	lo, hi := base*(1-p.Jitter), min(base*(1+p.Jitter), float64(p.Max))
+	return time.Duration(lo + (hi-lo)*rand.Float64())
+}
+
+// Exhausted reports whether no attempts are left after the given number of attempts
+func (p Policy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
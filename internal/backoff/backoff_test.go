@@ -0,0 +1,98 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDelayWithoutJitter(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: -1, want: 0},
+		{attempt: 0, want: 0},
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 1000, want: 10 * time.Second},
+		{attempt: 1 << 30, want: 10 * time.Second},
+		{attempt: math.MaxInt, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Delay(tt.attempt); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestDelayWithJitter(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.5}
+	tests := []struct {
+		attempt int
+		lo, hi  time.Duration
+	}{
+		{attempt: 1, lo: 500 * time.Millisecond, hi: 1500 * time.Millisecond},
+		{attempt: 3, lo: 2 * time.Second, hi: 6 * time.Second},
+		// 8s ± 4s is capped at Max
+		{attempt: 4, lo: 4 * time.Second, hi: 10 * time.Second},
+		{attempt: 5, lo: 5 * time.Second, hi: 10 * time.Second},
+		{attempt: 1000, lo: 5 * time.Second, hi: 10 * time.Second},
+		{attempt: 1 << 30, lo: 5 * time.Second, hi: 10 * time.Second},
+		{attempt: math.MaxInt, lo: 5 * time.Second, hi: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		seen := make(map[time.Duration]bool)
+		for range 200 {
+			got := p.Delay(tt.attempt)
+			if got < tt.lo || got > tt.hi {
+				t.Fatalf("Delay(%d) = %s, want within [%s, %s]", tt.attempt, got, tt.lo, tt.hi)
+			}
+			seen[got] = true
+		}
+		// Reconnects of many nodes must not collapse onto the same delay, even at the cap
+		if len(seen) < 100 {
+			t.Errorf("Delay(%d) produced only %d distinct delays out of 200", tt.attempt, len(seen))
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		maxAttempts, attempts int
+		want                  bool
+	}{
+		{maxAttempts: 0, attempts: 0, want: false},
+		{maxAttempts: 0, attempts: math.MaxInt, want: false},
+		{maxAttempts: 3, attempts: 2, want: false},
+		{maxAttempts: 3, attempts: 3, want: true},
+		{maxAttempts: 3, attempts: 4, want: true},
+	}
+	for _, tt := range tests {
+		p := Policy{MaxAttempts: tt.maxAttempts}
+		if got := p.Exhausted(tt.attempts); got != tt.want {
+			t.Errorf("Exhausted(%d) with max %d = %t, want %t", tt.attempts, tt.maxAttempts, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Policy{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for name, p := range map[string]Policy{
+		"zero initial":      {Max: time.Minute, Multiplier: 2},
+		"max below initial": {Initial: time.Minute, Max: time.Second, Multiplier: 2},
+		"small multiplier":  {Initial: time.Second, Max: time.Minute, Multiplier: 0.5},
+		"large jitter":      {Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 1.5},
+		"negative attempts": {Initial: time.Second, Max: time.Minute, Multiplier: 2, MaxAttempts: -1},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: Validate succeeded", name)
+		}
+	}
+}
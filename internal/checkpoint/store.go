@@ -0,0 +1,114 @@
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// NoVersion is the version of a checkpoint that has never been saved
+const NoVersion int32 = -1
+
+// ErrConflict is returned when the checkpoint was changed by someone else since it was loaded,
+// which usually means that another node has become the leader
+var ErrConflict = errors.New("checkpoint was modified concurrently")
+
+// Checkpoint is the saved progress of a task together with the znode version it was read at
+type Checkpoint struct {
+	Data    []byte
+	Version int32
+}
+
+// Store persists checkpoints of leader tasks
+type Store interface {
+	// Load returns the last saved checkpoint of the task, or an empty one with NoVersion
+	Load(task string) (Checkpoint, error)
+	// Save replaces the checkpoint if it still has the given version and returns the new version
+	Save(task string, data []byte, version int32) (int32, error)
+}
+
+var _ Store = &ZKStore{}
+
+// ZKStore keeps every checkpoint in a persistent znode <root>/<task>, so it survives leader changes
+type ZKStore struct {
+	conn *zk.Conn
+	root string
+}
+
+// NewZKStore creates a store rooted at the path, e.g. /election-state
+func NewZKStore(conn *zk.Conn, root string) *ZKStore {
+	return &ZKStore{
+		conn: conn,
+		root: "/" + strings.Trim(root, "/"),
+	}
+}
+
+func (s *ZKStore) Load(task string) (Checkpoint, error) {
+	path, err := s.path(task)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	data, stat, err := s.conn.Get(path)
+	if errors.Is(err, zk.ErrNoNode) {
+		return Checkpoint{Version: NoVersion}, nil
+	}
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("get checkpoint %s: %w", path, err)
+	}
+	return Checkpoint{
+		Data:    data,
+		Version: stat.Version,
+	}, nil
+}
+
+func (s *ZKStore) Save(task string, data []byte, version int32) (int32, error) {
+	path, err := s.path(task)
+	if err != nil {
+		return 0, err
+	}
+
+	if version == NoVersion {
+		if err := s.ensureRoot(); err != nil {
+			return 0, err
+		}
+		_, err := s.conn.Create(path, data, 0, zk.WorldACL(zk.PermAll))
+		if errors.Is(err, zk.ErrNodeExists) {
+			return 0, fmt.Errorf("create checkpoint %s: %w", path, ErrConflict)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("create checkpoint %s: %w", path, err)
+		}
+		return 0, nil
+	}
+
+	stat, err := s.conn.Set(path, data, version)
+	if errors.Is(err, zk.ErrBadVersion) || errors.Is(err, zk.ErrNoNode) {
+		return 0, fmt.Errorf("set checkpoint %s: %w", path, ErrConflict)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("set checkpoint %s: %w", path, err)
+	}
+	return stat.Version, nil
+}
+
+func (s *ZKStore) path(task string) (string, error) {
+	if task == "" || strings.Contains(task, "/") {
+		return "", fmt.Errorf("invalid checkpoint task name %q", task)
+	}
+	return s.root + "/" + task, nil
+}
+
+// ensureRoot creates the root znode together with its missing parents
+func (s *ZKStore) ensureRoot() error {
+	path := ""
+	for _, part := range strings.Split(strings.Trim(s.root, "/"), "/") {
+		path += "/" + part
+		_, err := s.conn.Create(path, nil, 0, zk.WorldACL(zk.PermAll))
+		if err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("create checkpoint root %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -3,9 +3,55 @@ package cmdargs
 import "time"
 
 type RunArgs struct {
-	ZookeeperServers []string
-	LeaderTimeout    time.Duration
-	AttempterTimeout time.Duration
-	FileDir          string
-	StorageCapacity  int
+	ZookeeperServers     []string
+	LeaderTimeout        time.Duration
+	AttempterTimeout     time.Duration
+	FileDir              string
+	StorageCapacity      int
+	NodeID               string
+	OutputFormat         string
+	FileNameTemplate     string
+	FileContentTemplate  string
+	Sink                 string
+	S3Endpoint           string
+	S3Bucket             string
+	S3Prefix             string
+	S3Region             string
+	S3AccessKey          string
+	S3SecretKey          string
+	CheckpointPath       string
+	LeaderSchedule       string
+	LeaderTimezone       string
+	MisfirePolicy        string
+	BackfillPolicy       string
+	Delivery             string
+	JobsFile             string
+	Partitions           []string
+	AssignmentPath       string
+	MaxFailures          int
+	MaxErrorRate         float64
+	ErrorRateWindow      time.Duration
+	ErrorRateMinRuns     int
+	FollowerCommand      string
+	StateMaxDuration     []string
+	StateStallTimeout    []string
+	WatchdogAction       string
+	AdminAddr            string
+	ReconnectInitial     time.Duration
+	ReconnectMax         time.Duration
+	ReconnectMultiplier  float64
+	ReconnectJitter      float64
+	ReconnectMaxAttempts int
+	Supervise            bool
+	RestartInitial       time.Duration
+	RestartMax           time.Duration
+	MaxRestarts          int
+	RestartWindow        time.Duration
+	MaintenanceFile      string
+	DrainTimeout         time.Duration
+}
+
+type GraphArgs struct {
+	Format     string
+	CountsFrom string
 }
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/admin"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// countsTimeout bounds the request of transition counts from a running node
+const countsTimeout = 10 * time.Second
+
+func InitGraphCommand(ctx context.Context) (*cobra.Command, error) {
+	cmdArgs := cmdargs.GraphArgs{}
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Prints the state machine diagram",
+		Long: `This command prints the states and transitions of the state machine, as declared
+		in the code, in Mermaid or Graphviz DOT format`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			format := viper.GetString("format")
+			countsFrom := viper.GetString("counts-from")
+
+			var counts map[run.Edge]int
+			if countsFrom != "" {
+				var err error
+				counts, err = fetchCounts(ctx, countsFrom)
+				if err != nil {
+					return fmt.Errorf("error on: fetching transition counts - %w", err)
+				}
+			}
+
+			switch format {
+			case "mermaid":
+				fmt.Fprint(cmd.OutOrStdout(), run.Mermaid(run.Transitions, counts))
+			case "dot":
+				fmt.Fprint(cmd.OutOrStdout(), run.DOT(run.Transitions, counts))
+			default:
+				return fmt.Errorf("unknown graph format %q, expected mermaid or dot", format)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cmdArgs.Format, "format", "mermaid", "Diagram format: mermaid or dot")
+	cmd.Flags().StringVar(&cmdArgs.CountsFrom, "counts-from", "", "Admin address of a running node, e.g. http://localhost:8081, to overlay live transition counts")
+
+	if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("counts-from", cmd.Flags().Lookup("counts-from")); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// fetchCounts reads the transition counts from the admin server of a running node
+func fetchCounts(ctx context.Context, addr string) (map[run.Edge]int, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	ctx, cancel := context.WithTimeout(ctx, countsTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/transitions", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var snapshot admin.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode transitions: %w", err)
+	}
+	counts := make(map[run.Edge]int, len(snapshot.Transitions))
+	for _, t := range snapshot.Transitions {
+		counts[run.Edge{From: t.From, To: t.To}] = t.Count
+	}
+	return counts, nil
+}
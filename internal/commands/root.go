@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+func InitRootCommand(ctx context.Context) (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:           "election",
+		Short:         "Leader election node based on ZooKeeper",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	runCmd, err := InitRunCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graphCmd, err := InitGraphCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.AddCommand(runCmd, graphCmd)
+	return cmd, nil
+}
@@ -4,13 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/admin"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backoff"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/commands/cmdargs"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/output"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/leader"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -29,13 +41,100 @@ func InitRunCommand(ctx context.Context) (*cobra.Command, error) {
 			attempterTimeout := viper.GetDuration("attempter-timeout")
 			fileDir := viper.GetString("file-dir")
 			storageCapacity := viper.GetInt("storage-capacity")
+			nodeID := viper.GetString("node-id")
+			outputFormat := viper.GetString("output-format")
+			fileNameTemplate := viper.GetString("file-name-template")
+			fileContentTemplate := viper.GetString("file-content-template")
+			sink := viper.GetString("sink")
+			s3Endpoint := viper.GetString("s3-endpoint")
+			s3Bucket := viper.GetString("s3-bucket")
+			s3Prefix := viper.GetString("s3-prefix")
+			s3Region := viper.GetString("s3-region")
+			s3AccessKey := viper.GetString("s3-access-key")
+			s3SecretKey := viper.GetString("s3-secret-key")
+			checkpointPath := viper.GetString("checkpoint-path")
+			leaderSchedule := viper.GetString("leader-schedule")
+			leaderTimezone := viper.GetString("leader-timezone")
+			misfirePolicy := viper.GetString("misfire-policy")
+			backfillPolicy := viper.GetString("backfill-policy")
+			delivery := viper.GetString("delivery")
+			jobsFile := viper.GetString("jobs-file")
+			partitions := splitList(viper.GetStringSlice("partitions"))
+			assignmentPath := viper.GetString("assignment-path")
+			maxFailures := viper.GetInt("max-failures")
+			maxErrorRate := viper.GetFloat64("max-error-rate")
+			errorRateWindow := viper.GetDuration("error-rate-window")
+			errorRateMinRuns := viper.GetInt("error-rate-min-runs")
+			followerCommand := viper.GetString("follower-command")
+			stateMaxDuration := splitList(viper.GetStringSlice("state-max-duration"))
+			stateStallTimeout := splitList(viper.GetStringSlice("state-stall-timeout"))
+			watchdogAction := viper.GetString("watchdog-action")
+			adminAddr := viper.GetString("admin-addr")
+			reconnectInitial := viper.GetDuration("reconnect-initial")
+			reconnectMax := viper.GetDuration("reconnect-max")
+			reconnectMultiplier := viper.GetFloat64("reconnect-multiplier")
+			reconnectJitter := viper.GetFloat64("reconnect-jitter")
+			reconnectMaxAttempts := viper.GetInt("reconnect-max-attempts")
+			supervise := viper.GetBool("supervise")
+			restartInitial := viper.GetDuration("restart-initial")
+			restartMax := viper.GetDuration("restart-max")
+			maxRestarts := viper.GetInt("max-restarts")
+			restartWindow := viper.GetDuration("restart-window")
+			maintenanceFile := viper.GetString("maintenance-file")
+			drainTimeout := viper.GetDuration("drain-timeout")
+			jobs, err := loadJobs(jobsFile)
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: loading jobs - %w", err))
+			}
 
 			configFile := config.Config{
-				ZookeeperServers: zookeeperServers,
-				LeaderTimeout:    leaderTimeout,
-				AttempterTimeout: attempterTimeout,
-				FileDir:          fileDir,
-				StorageCapacity:  storageCapacity,
+				ZookeeperServers:     zookeeperServers,
+				LeaderTimeout:        leaderTimeout,
+				AttempterTimeout:     attempterTimeout,
+				FileDir:              fileDir,
+				StorageCapacity:      storageCapacity,
+				NodeID:               nodeID,
+				OutputFormat:         outputFormat,
+				FileNameTemplate:     fileNameTemplate,
+				FileContentTemplate:  fileContentTemplate,
+				Sink:                 sink,
+				S3Endpoint:           s3Endpoint,
+				S3Bucket:             s3Bucket,
+				S3Prefix:             s3Prefix,
+				S3Region:             s3Region,
+				S3AccessKey:          s3AccessKey,
+				S3SecretKey:          s3SecretKey,
+				CheckpointPath:       checkpointPath,
+				LeaderSchedule:       leaderSchedule,
+				LeaderTimezone:       leaderTimezone,
+				MisfirePolicy:        misfirePolicy,
+				BackfillPolicy:       backfillPolicy,
+				Delivery:             delivery,
+				JobsFile:             jobsFile,
+				Jobs:                 jobs,
+				Partitions:           partitions,
+				AssignmentPath:       assignmentPath,
+				MaxFailures:          maxFailures,
+				MaxErrorRate:         maxErrorRate,
+				ErrorRateWindow:      errorRateWindow,
+				ErrorRateMinRuns:     errorRateMinRuns,
+				FollowerCommand:      followerCommand,
+				StateMaxDuration:     stateMaxDuration,
+				StateStallTimeout:    stateStallTimeout,
+				WatchdogAction:       watchdogAction,
+				AdminAddr:            adminAddr,
+				ReconnectInitial:     reconnectInitial,
+				ReconnectMax:         reconnectMax,
+				ReconnectMultiplier:  reconnectMultiplier,
+				ReconnectJitter:      reconnectJitter,
+				ReconnectMaxAttempts: reconnectMaxAttempts,
+				Supervise:            supervise,
+				RestartInitial:       restartInitial,
+				RestartMax:           restartMax,
+				MaxRestarts:          maxRestarts,
+				RestartWindow:        restartWindow,
+				MaintenanceFile:      maintenanceFile,
+				DrainTimeout:         drainTimeout,
 			}
 
 			dg := depgraph.New(configFile)
@@ -46,15 +145,120 @@ func InitRunCommand(ctx context.Context) (*cobra.Command, error) {
 
 			logger.Info("args successfully received", slog.String("servers", strings.Join(zookeeperServers, ", ")))
 
+			// Validate output format, templates and sink before joining the election
+			_, err = dg.GetRenderer()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting renderer - %w", err))
+			}
+			_, err = dg.GetSink()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting sink - %w", err))
+			}
+			_, err = dg.GetSchedule()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting schedule - %w", err))
+			}
+			misfire, err := schedule.ParseMisfirePolicy(misfirePolicy)
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: parsing misfire policy - %w", err))
+			}
+			backfill, err := leader.ParseBackfillPolicy(backfillPolicy)
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: parsing backfill policy - %w", err))
+			}
+			mode, err := ticks.ParseMode(delivery)
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: parsing delivery mode - %w", err))
+			}
+			if mode == ticks.ModeAtLeastOnce && misfire != schedule.MisfireAll && backfill == leader.BackfillNone {
+				return termination.New(termination.Configuration,
+					fmt.Errorf("error on: at-least-once delivery requires misfire-policy all or a backfill-policy"))
+			}
+			_, err = dg.GetJobs()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting jobs - %w", err))
+			}
+			_, err = dg.GetFollowerTasks()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting follower tasks - %w", err))
+			}
+			_, err = dg.GetFailurePolicy()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting failure policy - %w", err))
+			}
+			_, err = dg.GetReconnectPolicy()
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: getting reconnect policy - %w", err))
+			}
+
+			if drainTimeout <= 0 {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: drain timeout must be positive, got %s", drainTimeout))
+			}
+
+			restartPolicy := backoff.Policy{Initial: restartInitial, Max: restartMax, Multiplier: 2, Jitter: 0.2}
+			if err := restartPolicy.Validate(); err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: validating restart backoff - %w", err))
+			}
+			watchdog, err := run.ParseWatchdog(stateMaxDuration, stateStallTimeout, watchdogAction)
+			if err != nil {
+				return termination.New(termination.Configuration, fmt.Errorf("error on: parsing watchdog - %w", err))
+			}
+
+			// Stopping closes the connection, this covers runs that end without reaching it
+			conns, err := dg.GetConnManager()
+			if err != nil {
+				return fmt.Errorf("error on: getting connection manager - %w", err)
+			}
+			defer conns.Close()
+
+			maintenanceSwitch, err := dg.GetMaintenance()
+			if err != nil {
+				return fmt.Errorf("error on: getting maintenance switch - %w", err)
+			}
+			maintenanceCtx, stopMaintenance := context.WithCancel(ctx)
+			defer stopMaintenance()
+			go maintenanceSwitch.Run(maintenanceCtx)
+			go watchMaintenanceSignals(maintenanceCtx, maintenanceSwitch)
+
+			recorder, err := dg.GetRecorder()
+			if err != nil {
+				return fmt.Errorf("error on: getting recorder - %w", err)
+			}
+			if adminAddr != "" {
+				adminServer, err := dg.GetAdminServer()
+				if err != nil {
+					return fmt.Errorf("error on: getting admin server - %w", err)
+				}
+				adminCtx, stopAdmin := context.WithCancel(ctx)
+				defer stopAdmin()
+				go func() {
+					if err := adminServer.Run(adminCtx); err != nil {
+						logger.Error("Admin server failed", slog.String("error", err.Error()))
+					}
+				}()
+			}
+
 			runner := run.NewLoopRunner(logger, dg)
+			runner.Observe(run.NewLogObserver(logger))
+			runner.Observe(recorder)
+			runner.SetWatchdog(watchdog)
 			if err != nil {
 				return fmt.Errorf("error on: getting runner - %w", err)
 			}
-			firstState, err := dg.GetInitState()
-			if err != nil {
-				return fmt.Errorf("error on: getting first state - %w", err)
+			if supervise {
+				supervisor := run.NewSupervisor(logger, runner, dg, restartPolicy,
+					run.RestartLimit{MaxRestarts: maxRestarts, Window: restartWindow}, conns.Reopen)
+				supervisor.Observe(recorder)
+				err = supervisor.Run(ctx)
+			} else {
+				var firstState states.AutomataState
+				firstState, err = dg.GetInitState()
+				if err != nil {
+					return fmt.Errorf("error on: getting first state - %w", err)
+				}
+				err = runner.Run(ctx, firstState)
 			}
-			err = runner.Run(ctx, firstState)
+			logSummary(ctx, logger, recorder, err)
 			if err != nil {
 				return fmt.Errorf("error on: running states - %w", err)
 			}
@@ -68,6 +272,47 @@ func InitRunCommand(ctx context.Context) (*cobra.Command, error) {
 	cmd.Flags().DurationVar(&cmdArgs.AttempterTimeout, "attempter-timeout", 10*time.Second, "Attempter timeout duration")
 	cmd.Flags().StringVar(&cmdArgs.FileDir, "file-dir", "/tmp/election", "Directory where leader writes files")
 	cmd.Flags().IntVar(&cmdArgs.StorageCapacity, "storage-capacity", 10, "Maximum number of files in file-dir")
+	cmd.Flags().StringVar(&cmdArgs.NodeID, "node-id", defaultNodeID(), "Identifier of this node, defaults to the hostname")
+	cmd.Flags().StringVar(&cmdArgs.OutputFormat, "output-format", string(output.FormatText), "Format of the leader file: text, json or ndjson")
+	cmd.Flags().StringVar(&cmdArgs.FileNameTemplate, "file-name-template", "", "Template of the leader file name, defaults to one matching the output format")
+	cmd.Flags().StringVar(&cmdArgs.FileContentTemplate, "file-content-template", output.DefaultContentTemplate, "Template of the leader file content")
+	cmd.Flags().StringVar(&cmdArgs.Sink, "sink", "local", "Storage of the leader files: local or s3")
+	cmd.Flags().StringVar(&cmdArgs.S3Endpoint, "s3-endpoint", "", "Endpoint of the S3-compatible storage, defaults to AWS in s3-region")
+	cmd.Flags().StringVar(&cmdArgs.S3Bucket, "s3-bucket", "", "Bucket where leader writes files")
+	cmd.Flags().StringVar(&cmdArgs.S3Prefix, "s3-prefix", "election", "Key prefix of the leader files in the bucket")
+	cmd.Flags().StringVar(&cmdArgs.S3Region, "s3-region", "us-east-1", "Region of the bucket")
+	cmd.Flags().StringVar(&cmdArgs.S3AccessKey, "s3-access-key", "", "Access key of the S3-compatible storage")
+	cmd.Flags().StringVar(&cmdArgs.S3SecretKey, "s3-secret-key", "", "Secret key of the S3-compatible storage")
+	cmd.Flags().StringVar(&cmdArgs.LeaderSchedule, "leader-schedule", "", "Cron expression of the leader work, defaults to every leader-timeout")
+	cmd.Flags().StringVar(&cmdArgs.LeaderTimezone, "leader-timezone", "UTC", "Timezone of the leader-schedule cron expression")
+	cmd.Flags().StringVar(&cmdArgs.MisfirePolicy, "misfire-policy", string(schedule.MisfireSkip), "What to do with activations missed during failover: skip, once or all")
+	cmd.Flags().StringVar(&cmdArgs.BackfillPolicy, "backfill-policy", string(leader.BackfillNone), "What a new leader does with intervals that got no output: none, flag or fill")
+	cmd.Flags().StringVar(&cmdArgs.Delivery, "delivery", string(ticks.ModeOff), "Tick claiming of the leader output across leader changes: off, at-most-once or at-least-once")
+	cmd.Flags().StringVar(&cmdArgs.JobsFile, "jobs-file", "", "YAML, JSON or TOML file with additional jobs run by the leader")
+	cmd.Flags().StringSliceVar(&cmdArgs.Partitions, "partitions", nil, "Partitions the leader distributes across all candidates, disabled if empty")
+	cmd.Flags().StringVar(&cmdArgs.AssignmentPath, "assignment-path", "/election-assignment", "Zookeeper path where the leader writes partition assignment")
+	cmd.Flags().IntVar(&cmdArgs.MaxFailures, "max-failures", 0, "Consecutive failures of a leader job after which the node gives up leadership, 0 disables")
+	cmd.Flags().Float64Var(&cmdArgs.MaxErrorRate, "max-error-rate", 0, "Error rate of a leader job within error-rate-window after which the node gives up leadership, 0 disables")
+	cmd.Flags().DurationVar(&cmdArgs.ErrorRateWindow, "error-rate-window", 5*time.Minute, "Window in which the error rate of leader jobs is measured")
+	cmd.Flags().IntVar(&cmdArgs.ErrorRateMinRuns, "error-rate-min-runs", 10, "Minimum number of runs within error-rate-window before the error rate is evaluated")
+	cmd.Flags().StringVar(&cmdArgs.FollowerCommand, "follower-command", "", "Command run while the node waits for leadership, killed when it becomes the leader")
+	cmd.Flags().StringSliceVar(&cmdArgs.StateMaxDuration, "state-max-duration", nil, "Longest time in a state as State=duration, for example Init=60s,Failover=60s")
+	cmd.Flags().StringSliceVar(&cmdArgs.StateStallTimeout, "state-stall-timeout", nil, "Longest time in a state without progress as State=duration, for example Attempter=10m")
+	cmd.Flags().DurationVar(&cmdArgs.ReconnectInitial, "reconnect-initial", time.Second, "Delay before the second reconnection attempt in the failover state")
+	cmd.Flags().DurationVar(&cmdArgs.ReconnectMax, "reconnect-max", 30*time.Second, "Cap of the delay between reconnection attempts")
+	cmd.Flags().Float64Var(&cmdArgs.ReconnectMultiplier, "reconnect-multiplier", 2, "Growth of the delay after every reconnection attempt")
+	cmd.Flags().Float64Var(&cmdArgs.ReconnectJitter, "reconnect-jitter", 0.2, "Random spread of the reconnection delay, a fraction between 0 and 1")
+	cmd.Flags().IntVar(&cmdArgs.ReconnectMaxAttempts, "reconnect-max-attempts", 0, "Reconnection attempts before stopping, unlimited if 0")
+	cmd.Flags().BoolVar(&cmdArgs.Supervise, "supervise", false, "Restart the state machine from Init after it fails instead of exiting")
+	cmd.Flags().DurationVar(&cmdArgs.RestartInitial, "restart-initial", time.Second, "Delay before the first restart of the state machine")
+	cmd.Flags().DurationVar(&cmdArgs.RestartMax, "restart-max", time.Minute, "Cap of the delay between restarts")
+	cmd.Flags().IntVar(&cmdArgs.MaxRestarts, "max-restarts", 5, "Restarts allowed within restart-window before the node exits, unlimited if 0")
+	cmd.Flags().DurationVar(&cmdArgs.RestartWindow, "restart-window", 10*time.Minute, "Window of the restart rate limit")
+	cmd.Flags().StringVar(&cmdArgs.MaintenanceFile, "maintenance-file", "", "Flag file that keeps the node in maintenance while it exists, disabled if empty")
+	cmd.Flags().DurationVar(&cmdArgs.DrainTimeout, "drain-timeout", 10*time.Second, "Time the leader is given on shutdown or maintenance to finish in-flight work before it is cancelled")
+	cmd.Flags().StringVar(&cmdArgs.AdminAddr, "admin-addr", "", "Address of the admin HTTP server with /transitions and /healthz, disabled if empty")
+	cmd.Flags().StringVar(&cmdArgs.WatchdogAction, "watchdog-action", string(run.WatchdogLog), "Action on a state exceeding its limit: log, failover or restart")
+	cmd.Flags().StringVar(&cmdArgs.CheckpointPath, "checkpoint-path", "/election-state", "Zookeeper path where leader tasks store checkpoints")
 
 	// Bind flags to viper
 
@@ -86,9 +331,208 @@ func InitRunCommand(ctx context.Context) (*cobra.Command, error) {
 	if err := viper.BindPFlag("storage-capacity", cmd.Flags().Lookup("storage-capacity")); err != nil {
 		return nil, err
 	}
+	if err := viper.BindPFlag("node-id", cmd.Flags().Lookup("node-id")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("output-format", cmd.Flags().Lookup("output-format")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("file-name-template", cmd.Flags().Lookup("file-name-template")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("file-content-template", cmd.Flags().Lookup("file-content-template")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("sink", cmd.Flags().Lookup("sink")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-endpoint", cmd.Flags().Lookup("s3-endpoint")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-bucket", cmd.Flags().Lookup("s3-bucket")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-prefix", cmd.Flags().Lookup("s3-prefix")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-region", cmd.Flags().Lookup("s3-region")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-access-key", cmd.Flags().Lookup("s3-access-key")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("s3-secret-key", cmd.Flags().Lookup("s3-secret-key")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("checkpoint-path", cmd.Flags().Lookup("checkpoint-path")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("leader-schedule", cmd.Flags().Lookup("leader-schedule")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("leader-timezone", cmd.Flags().Lookup("leader-timezone")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("misfire-policy", cmd.Flags().Lookup("misfire-policy")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("backfill-policy", cmd.Flags().Lookup("backfill-policy")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("delivery", cmd.Flags().Lookup("delivery")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("jobs-file", cmd.Flags().Lookup("jobs-file")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("partitions", cmd.Flags().Lookup("partitions")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("assignment-path", cmd.Flags().Lookup("assignment-path")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("max-failures", cmd.Flags().Lookup("max-failures")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("max-error-rate", cmd.Flags().Lookup("max-error-rate")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("error-rate-window", cmd.Flags().Lookup("error-rate-window")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("error-rate-min-runs", cmd.Flags().Lookup("error-rate-min-runs")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("follower-command", cmd.Flags().Lookup("follower-command")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("state-max-duration", cmd.Flags().Lookup("state-max-duration")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("state-stall-timeout", cmd.Flags().Lookup("state-stall-timeout")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("watchdog-action", cmd.Flags().Lookup("watchdog-action")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("admin-addr", cmd.Flags().Lookup("admin-addr")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("maintenance-file", cmd.Flags().Lookup("maintenance-file")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("drain-timeout", cmd.Flags().Lookup("drain-timeout")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("supervise", cmd.Flags().Lookup("supervise")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("restart-initial", cmd.Flags().Lookup("restart-initial")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("restart-max", cmd.Flags().Lookup("restart-max")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("max-restarts", cmd.Flags().Lookup("max-restarts")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("restart-window", cmd.Flags().Lookup("restart-window")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("reconnect-initial", cmd.Flags().Lookup("reconnect-initial")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("reconnect-max", cmd.Flags().Lookup("reconnect-max")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("reconnect-multiplier", cmd.Flags().Lookup("reconnect-multiplier")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("reconnect-jitter", cmd.Flags().Lookup("reconnect-jitter")); err != nil {
+		return nil, err
+	}
+	if err := viper.BindPFlag("reconnect-max-attempts", cmd.Flags().Lookup("reconnect-max-attempts")); err != nil {
+		return nil, err
+	}
 	return cmd, nil
 }
 
+// splitList flattens comma-separated values, environment variables arrive as a single string
+func splitList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+// loadJobs reads the list of jobs from the "jobs" key of the file
+func loadJobs(path string) ([]config.JobSpec, error) {
+	if path == "" {
+		return nil, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read jobs file: %w", err)
+	}
+	var jobs []config.JobSpec
+	if err := v.UnmarshalKey("jobs", &jobs); err != nil {
+		return nil, fmt.Errorf("decode jobs file: %w", err)
+	}
+	return jobs, nil
+}
+
+// watchMaintenanceSignals enables the maintenance on SIGUSR1 and releases it on SIGUSR2
+func watchMaintenanceSignals(ctx context.Context, sw *maintenance.Switch) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			sw.Set(sig == syscall.SIGUSR1)
+		}
+	}
+}
+
+// logSummary writes the final log line of the node with its termination reason and exit code
+func logSummary(ctx context.Context, logger *slog.Logger, recorder *admin.Recorder, err error) {
+	reason := termination.ReasonOf(err)
+	snapshot := recorder.Snapshot()
+	transitions := 0
+	for _, t := range snapshot.Transitions {
+		transitions += t.Count
+	}
+	attrs := []slog.Attr{
+		slog.String("reason", string(reason)),
+		slog.Int("exit_code", reason.ExitCode()),
+		slog.Duration("uptime", output.Uptime()),
+		slog.Int("transitions", transitions),
+		slog.Int("restarts", snapshot.Restarts),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "Node finished", attrs...)
+}
+
+func defaultNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
 func init() {
 	// Read in environment variables that match
 	viper.AutomaticEnv()
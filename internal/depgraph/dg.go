@@ -1,22 +1,44 @@
 package depgraph
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/admin"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backoff"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/follower"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/output"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/partition"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/scheduler"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/attempter"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/draining"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/failover"
 	initial2 "github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/init"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/leader"
+	maintenancestate "github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/maintenance"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states/stopping"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/zkconn"
 	"github.com/go-zookeeper/zk"
 )
 
+const electionPath = "/election"
+
 type dgEntity[T any] struct {
 	sync.Once
 	value   T
@@ -35,30 +57,84 @@ func (e *dgEntity[T]) get(init func() (T, error)) (T, error) {
 }
 
 type DepGraph struct {
-	Config         config.Config
-	logger         *dgEntity[*slog.Logger]
-	stateRunner    *dgEntity[*run.LoopRunner]
-	emptyState     *dgEntity[states.AutomataState]
-	initState      *dgEntity[states.AutomataState]
-	attempterState *dgEntity[states.AutomataState]
-	leaderState    *dgEntity[states.AutomataState]
-	failoverState  *dgEntity[states.AutomataState]
-	stoppingState  *dgEntity[states.AutomataState]
-	conn           *zk.Conn
+	Config        config.Config
+	logger        *dgEntity[*slog.Logger]
+	stateRunner   *dgEntity[*run.LoopRunner]
+	renderer      *dgEntity[*output.Renderer]
+	sink          *dgEntity[output.Sink]
+	schedule      *dgEntity[schedule.Schedule]
+	jobs          *dgEntity[[]scheduler.Job]
+	recorder      *dgEntity[*admin.Recorder]
+	adminServer   *dgEntity[*admin.Server]
+	extraJobs     []scheduler.Job
+	partitionTask partition.Task
+	followerTasks []follower.Task
+	conns         *dgEntity[*zkconn.Manager]
+	maintenance   *dgEntity[*maintenance.Switch]
+	electionNode  string
+	candidateID   string
 }
 
 func New(config config.Config) *DepGraph {
 	return &DepGraph{
-		Config:         config,
-		logger:         &dgEntity[*slog.Logger]{},
-		stateRunner:    &dgEntity[*run.LoopRunner]{},
-		emptyState:     &dgEntity[states.AutomataState]{},
-		initState:      &dgEntity[states.AutomataState]{},
-		attempterState: &dgEntity[states.AutomataState]{},
-		leaderState:    &dgEntity[states.AutomataState]{},
-		failoverState:  &dgEntity[states.AutomataState]{},
-		stoppingState:  &dgEntity[states.AutomataState]{},
+		Config:      config,
+		logger:      &dgEntity[*slog.Logger]{},
+		stateRunner: &dgEntity[*run.LoopRunner]{},
+		renderer:    &dgEntity[*output.Renderer]{},
+		sink:        &dgEntity[output.Sink]{},
+		schedule:    &dgEntity[schedule.Schedule]{},
+		jobs:        &dgEntity[[]scheduler.Job]{},
+		recorder:    &dgEntity[*admin.Recorder]{},
+		adminServer: &dgEntity[*admin.Server]{},
+		conns:       &dgEntity[*zkconn.Manager]{},
+		maintenance: &dgEntity[*maintenance.Switch]{},
+		candidateID: newCandidateID(),
+	}
+}
+
+// newCandidateID returns the GUID that names the election znodes of this process,
+// so the attempter finds its own znode again after a connection loss
+func newCandidateID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// GetConnManager returns the single owner of the ZooKeeper connection
+func (dg *DepGraph) GetConnManager() (*zkconn.Manager, error) {
+	return dg.conns.get(func() (*zkconn.Manager, error) {
+		logger, err := dg.GetLogger()
+		if err != nil {
+			return nil, fmt.Errorf("error on: getting logger - %w", err)
+		}
+		return zkconn.New(logger, dg.Config.ZookeeperServers), nil
+	})
+}
+
+// GetMaintenance returns the switch that keeps the node out of the election
+func (dg *DepGraph) GetMaintenance() (*maintenance.Switch, error) {
+	return dg.maintenance.get(func() (*maintenance.Switch, error) {
+		logger, err := dg.GetLogger()
+		if err != nil {
+			return nil, fmt.Errorf("error on: getting logger - %w", err)
+		}
+		return maintenance.New(logger, dg.Config.MaintenanceFile), nil
+	})
+}
+
+// currentConn returns the connection borrowed by new states
+func (dg *DepGraph) currentConn() (*zk.Conn, error) {
+	conns, err := dg.GetConnManager()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting connection manager - %w", err)
 	}
+	conn := conns.Current()
+	if conn == nil {
+		return nil, fmt.Errorf("error on: Zookeeper connection not established")
+	}
+	return conn, nil
 }
 
 func (dg *DepGraph) GetLogger() (*slog.Logger, error) {
@@ -67,70 +143,408 @@ func (dg *DepGraph) GetLogger() (*slog.Logger, error) {
 	})
 }
 
+// GetInitState builds a new Init state. Like all states it is built on every transition,
+// so states always capture the current connection and election node
 func (dg *DepGraph) GetInitState() (states.AutomataState, error) {
-	return dg.initState.get(func() (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger - %w", err)
+	}
+	conns, err := dg.GetConnManager()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting connection manager - %w", err)
+	}
+	sw, err := dg.GetMaintenance()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting maintenance switch - %w", err)
+	}
+	return initial2.New(logger, dg.Config, conns, sw, dg), nil
+}
+
+func (dg *DepGraph) GetAttempterState() (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger - %w", err)
+	}
+	conn, err := dg.currentConn()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := dg.GetFollowerTasks()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting follower tasks - %w", err)
+	}
+	var followers *follower.Runner
+	if len(tasks) > 0 {
+		followers = follower.NewRunner(logger, tasks, dg.Config.AttempterTimeout)
+	}
+	sw, err := dg.GetMaintenance()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting maintenance switch - %w", err)
+	}
+	return attempter.New(logger, dg.Config, conn, dg.candidateID, dg.partitionWorker(logger, conn), followers, sw, dg), nil
+}
+
+func (dg *DepGraph) GetLeaderState() (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger %w", err)
+	}
+	renderer, err := dg.GetRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting renderer %w", err)
+	}
+	sink, err := dg.GetSink()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting sink %w", err)
+	}
+	conn, err := dg.currentConn()
+	if err != nil {
+		return nil, err
+	}
+	sched, err := dg.GetSchedule()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting schedule %w", err)
+	}
+	misfire, err := schedule.ParseMisfirePolicy(dg.Config.MisfirePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("error on: parsing misfire policy %w", err)
+	}
+	backfill, err := leader.ParseBackfillPolicy(dg.Config.BackfillPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("error on: parsing backfill policy %w", err)
+	}
+	delivery, err := ticks.ParseMode(dg.Config.Delivery)
+	if err != nil {
+		return nil, fmt.Errorf("error on: parsing delivery mode %w", err)
+	}
+	jobs, err := dg.GetJobs()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting jobs %w", err)
+	}
+	node, err := dg.GetElectionNode()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting election node %w", err)
+	}
+	sw, err := dg.GetMaintenance()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting maintenance switch %w", err)
+	}
+	checkpoints := checkpoint.NewZKStore(conn, dg.Config.CheckpointPath)
+	var coordinator *partition.Coordinator
+	if len(dg.Config.Partitions) > 0 {
+		coordinator = partition.NewCoordinator(logger, conn, electionPath, dg.Config.AssignmentPath,
+			dg.Config.Partitions, dg.Config.AttempterTimeout)
+	}
+	policy, err := dg.GetFailurePolicy()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting failure policy %w", err)
+	}
+	return leader.New(logger, dg.Config, conn, node, renderer, sink, checkpoints, sched, misfire, backfill, delivery, jobs, policy,
+		coordinator, dg.partitionWorker(logger, conn), sw, dg), nil
+}
+
+func (dg *DepGraph) GetFailoverState() (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger %w", err)
+	}
+	conns, err := dg.GetConnManager()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting connection manager %w", err)
+	}
+	policy, err := dg.GetReconnectPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting reconnect policy %w", err)
+	}
+	return failover.New(logger, dg.Config, conns, policy, dg), nil
+}
+
+// GetReconnectPolicy returns the backoff of reconnection attempts in the Failover state
+func (dg *DepGraph) GetReconnectPolicy() (backoff.Policy, error) {
+	policy := backoff.Policy{
+		Initial:     dg.Config.ReconnectInitial,
+		Max:         dg.Config.ReconnectMax,
+		Multiplier:  dg.Config.ReconnectMultiplier,
+		Jitter:      dg.Config.ReconnectJitter,
+		MaxAttempts: dg.Config.ReconnectMaxAttempts,
+	}
+	if err := policy.Validate(); err != nil {
+		return backoff.Policy{}, err
+	}
+	return policy, nil
+}
+
+func (dg *DepGraph) GetFailurePolicy() (scheduler.FailurePolicy, error) {
+	policy := scheduler.FailurePolicy{
+		MaxConsecutive: dg.Config.MaxFailures,
+		MaxErrorRate:   dg.Config.MaxErrorRate,
+		Window:         dg.Config.ErrorRateWindow,
+		MinRuns:        dg.Config.ErrorRateMinRuns,
+	}
+	if err := policy.Validate(); err != nil {
+		return scheduler.FailurePolicy{}, err
+	}
+	return policy, nil
+}
+
+func (dg *DepGraph) GetStoppingState() (states.AutomataState, error) {
+	return dg.stoppingState(nil)
+}
+
+func (dg *DepGraph) GetDrainingState(work states.Drainer) (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger %w", err)
+	}
+	conn, err := dg.currentConn()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting connection %w", err)
+	}
+	node, err := dg.GetElectionNode()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting election node %w", err)
+	}
+	return draining.New(logger, conn, node, dg.Config.DrainTimeout, work, dg), nil
+}
+
+func (dg *DepGraph) GetMaintenanceState() (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger %w", err)
+	}
+	sw, err := dg.GetMaintenance()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting maintenance switch %w", err)
+	}
+	return maintenancestate.New(logger, sw, dg), nil
+}
+
+func (dg *DepGraph) GetErrorState(err error) (states.AutomataState, error) {
+	if zkconn.Classify(err) == zkconn.ActionStop {
+		return dg.Terminate(zkconn.TerminationReason(err), err)
+	}
+	return dg.GetFailoverState()
+}
+
+func (dg *DepGraph) Terminate(reason termination.Reason, err error) (states.AutomataState, error) {
+	return dg.stoppingState(termination.New(reason, err))
+}
+
+func (dg *DepGraph) stoppingState(cause *termination.Error) (states.AutomataState, error) {
+	logger, err := dg.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting logger %w", err)
+	}
+	conns, err := dg.GetConnManager()
+	if err != nil {
+		return nil, fmt.Errorf("error on: getting connection manager %w", err)
+	}
+	return stopping.New(logger, conns, dg.Config, cause, dg), nil
+}
+
+func (dg *DepGraph) GetRunner() (run.Runner, error) {
+	return dg.stateRunner.get(func() (*run.LoopRunner, error) {
 		logger, err := dg.GetLogger()
 		if err != nil {
-			return nil, fmt.Errorf("error on: getting logger - %w", err)
+			return nil, fmt.Errorf("error on: getting logger: %w", err)
 		}
-		return initial2.New(logger, dg.Config, nil, dg), nil
+		return run.NewLoopRunner(logger, dg), nil
 	})
 }
 
-func (dg *DepGraph) GetAttempterState() (states.AutomataState, error) {
-	return dg.attempterState.get(func() (states.AutomataState, error) {
+// GetRecorder returns the observer counting transitions of the state machine
+func (dg *DepGraph) GetRecorder() (*admin.Recorder, error) {
+	return dg.recorder.get(func() (*admin.Recorder, error) {
+		return admin.NewRecorder(), nil
+	})
+}
+
+// GetAdminServer returns the admin HTTP server, it must only be requested when an admin address is configured
+func (dg *DepGraph) GetAdminServer() (*admin.Server, error) {
+	return dg.adminServer.get(func() (*admin.Server, error) {
+		if dg.Config.AdminAddr == "" {
+			return nil, errors.New("admin address is not configured")
+		}
 		logger, err := dg.GetLogger()
 		if err != nil {
 			return nil, fmt.Errorf("error on: getting logger - %w", err)
 		}
-		if dg.conn == nil {
-			return nil, fmt.Errorf("error on: Zookeeper connection not established")
+		recorder, err := dg.GetRecorder()
+		if err != nil {
+			return nil, fmt.Errorf("error on: getting recorder - %w", err)
+		}
+		sw, err := dg.GetMaintenance()
+		if err != nil {
+			return nil, fmt.Errorf("error on: getting maintenance switch - %w", err)
 		}
-		return attempter.New(logger, dg.Config, dg.conn, dg), nil
+		return admin.New(logger, dg.Config.AdminAddr, recorder, sw), nil
 	})
 }
 
-func (dg *DepGraph) GetLeaderState() (states.AutomataState, error) {
-	return dg.leaderState.get(func() (states.AutomataState, error) {
-		logger, err := dg.GetLogger()
+func (dg *DepGraph) GetRenderer() (*output.Renderer, error) {
+	return dg.renderer.get(func() (*output.Renderer, error) {
+		format, err := output.ParseFormat(dg.Config.OutputFormat)
 		if err != nil {
-			return nil, fmt.Errorf("error on: getting logger %w", err)
+			return nil, fmt.Errorf("error on: parsing output format - %w", err)
 		}
-		return leader.New(logger, dg.Config, dg), nil
+		return output.NewRenderer(format, dg.Config.FileNameTemplate, dg.Config.FileContentTemplate)
 	})
 }
 
-func (dg *DepGraph) GetFailoverState() (states.AutomataState, error) {
-	return dg.failoverState.get(func() (states.AutomataState, error) {
-		logger, err := dg.GetLogger()
+func (dg *DepGraph) GetSink() (output.Sink, error) {
+	return dg.sink.get(func() (output.Sink, error) {
+		switch dg.Config.Sink {
+		case "", "local":
+			return output.NewLocalSink(dg.Config.FileDir), nil
+		case "s3":
+			return output.NewS3Sink(output.S3Config{
+				Endpoint:  dg.Config.S3Endpoint,
+				Bucket:    dg.Config.S3Bucket,
+				Prefix:    dg.Config.S3Prefix,
+				Region:    dg.Config.S3Region,
+				AccessKey: dg.Config.S3AccessKey,
+				SecretKey: dg.Config.S3SecretKey,
+			}, nil)
+		default:
+			return nil, fmt.Errorf("error on: unknown sink %q", dg.Config.Sink)
+		}
+	})
+}
+
+func (dg *DepGraph) GetSchedule() (schedule.Schedule, error) {
+	return dg.schedule.get(func() (schedule.Schedule, error) {
+		if dg.Config.LeaderSchedule == "" {
+			interval, err := schedule.Every(dg.Config.LeaderTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("error on: creating interval schedule - %w", err)
+			}
+			return interval, nil
+		}
+		loc, err := time.LoadLocation(dg.Config.LeaderTimezone)
 		if err != nil {
-			return nil, fmt.Errorf("error on: getting logger %w", err)
+			return nil, fmt.Errorf("error on: loading timezone - %w", err)
 		}
-		return failover.New(logger, dg.Config, dg), nil
+		return schedule.Parse(dg.Config.LeaderSchedule, loc)
 	})
 }
 
-func (dg *DepGraph) GetStoppingState() (states.AutomataState, error) {
-	return dg.stoppingState.get(func() (states.AutomataState, error) {
+// RegisterJob adds a job implemented in code to the jobs run by the leader.
+// It must be called before the state machine is started. A long-running job keeps its progress
+// in Activation.Progress, so a new leader resumes it, and returns the error of a conflicting Save
+func (dg *DepGraph) RegisterJob(job scheduler.Job) {
+	dg.extraJobs = append(dg.extraJobs, job)
+}
+
+func (dg *DepGraph) GetJobs() ([]scheduler.Job, error) {
+	return dg.jobs.get(func() ([]scheduler.Job, error) {
 		logger, err := dg.GetLogger()
 		if err != nil {
 			return nil, fmt.Errorf("error on: getting logger %w", err)
 		}
-		return stopping.New(logger, dg.conn, dg.Config, dg), nil
+		jobs := append([]scheduler.Job(nil), dg.extraJobs...)
+		for _, spec := range dg.Config.Jobs {
+			job, err := jobFromSpec(logger, spec)
+			if err != nil {
+				return nil, fmt.Errorf("error on: creating job %s - %w", spec.Name, err)
+			}
+			jobs = append(jobs, job)
+		}
+		return jobs, nil
 	})
 }
 
-func (dg *DepGraph) GetRunner() (run.Runner, error) {
-	return dg.stateRunner.get(func() (*run.LoopRunner, error) {
-		logger, err := dg.GetLogger()
+func jobFromSpec(logger *slog.Logger, spec config.JobSpec) (scheduler.Job, error) {
+	timezone := spec.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return scheduler.Job{}, fmt.Errorf("loading timezone: %w", err)
+	}
+	sched, err := schedule.Parse(spec.Schedule, loc)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	delivery, err := ticks.ParseMode(spec.Delivery)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	misfire := spec.Misfire
+	if misfire == "" {
+		misfire = string(schedule.MisfireSkip)
+		if delivery == ticks.ModeAtLeastOnce {
+			misfire = string(schedule.MisfireAll)
+		}
+	}
+	misfirePolicy, err := schedule.ParseMisfirePolicy(misfire)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	run, err := scheduler.Command(logger, spec.Name, spec.Command)
+	if err != nil {
+		return scheduler.Job{}, err
+	}
+	return scheduler.Job{
+		Name:        spec.Name,
+		Schedule:    sched,
+		Misfire:     misfirePolicy,
+		Timeout:     spec.Timeout,
+		Concurrency: spec.Concurrency,
+		Delivery:    delivery,
+		Run:         run,
+	}, nil
+}
+
+// RegisterFollowerTask adds a task run while the node waits for leadership.
+// It must be called before the state machine is started
+func (dg *DepGraph) RegisterFollowerTask(task follower.Task) {
+	dg.followerTasks = append(dg.followerTasks, task)
+}
+
+func (dg *DepGraph) GetFollowerTasks() ([]follower.Task, error) {
+	tasks := append([]follower.Task(nil), dg.followerTasks...)
+	if args := strings.Fields(dg.Config.FollowerCommand); len(args) > 0 {
+		task, err := follower.Command(args)
 		if err != nil {
-			return nil, fmt.Errorf("error on: getting logger: %w", err)
+			return nil, fmt.Errorf("error on: creating follower command - %w", err)
 		}
-		return run.NewLoopRunner(logger, dg), nil
-	})
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// SetPartitionTask replaces the task processing partitions assigned to this node.
+// It must be called before the state machine is started
+func (dg *DepGraph) SetPartitionTask(task partition.Task) {
+	dg.partitionTask = task
 }
 
-func (dg *DepGraph) SetConn(conn *zk.Conn) error {
-	dg.conn = conn
+// partitionWorker returns nil when partitioning is disabled
+func (dg *DepGraph) partitionWorker(logger *slog.Logger, conn *zk.Conn) *partition.Worker {
+	if len(dg.Config.Partitions) == 0 {
+		return nil
+	}
+	task := dg.partitionTask
+	if task == nil {
+		task = partition.LogTask(logger)
+	}
+	return partition.NewWorker(logger, conn, dg.Config.AssignmentPath, task, dg.Config.AttempterTimeout)
+}
+
+func (dg *DepGraph) SetElectionNode(node string) error {
+	dg.electionNode = node
 	return nil
 }
+
+func (dg *DepGraph) ClearElectionNode() {
+	dg.electionNode = ""
+}
+
+func (dg *DepGraph) GetElectionNode() (string, error) {
+	if dg.electionNode == "" {
+		return "", fmt.Errorf("error on: election node is not created")
+	}
+	return dg.electionNode, nil
+}
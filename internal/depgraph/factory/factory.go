@@ -1,8 +1,8 @@
 package factory
 
 import (
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
-	"github.com/go-zookeeper/zk"
 )
 
 type StateFactory interface {
@@ -11,5 +11,16 @@ type StateFactory interface {
 	GetAttempterState() (states.AutomataState, error)
 	GetLeaderState() (states.AutomataState, error)
 	GetStoppingState() (states.AutomataState, error)
-	SetConn(conn *zk.Conn) error
+	// GetDrainingState returns the state that drains the work of the leader before it stops
+	GetDrainingState(work states.Drainer) (states.AutomataState, error)
+	GetMaintenanceState() (states.AutomataState, error)
+	// Terminate returns the Stopping state that ends the machine with the reason
+	Terminate(reason termination.Reason, err error) (states.AutomataState, error)
+	// GetErrorState returns the state that handles the ZooKeeper error: Stopping for fatal errors, Failover otherwise
+	GetErrorState(err error) (states.AutomataState, error)
+	// SetElectionNode records the candidate znode of this node, Stopping deletes it
+	SetElectionNode(node string) error
+	GetElectionNode() (string, error)
+	// ClearElectionNode forgets the candidate znode after it was deleted, so a stale path is never reused
+	ClearElectionNode()
 }
@@ -0,0 +1,106 @@
+package follower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Task runs while the node is a candidate waiting for leadership, e.g. to keep caches warm.
+// Its context is cancelled when the node becomes the leader or stops
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Runner starts follower tasks and restarts them after failures
+type Runner struct {
+	logger *slog.Logger
+	tasks  []Task
+	retry  time.Duration
+}
+
+// NewRunner creates a runner of the tasks. A task that returns before its context is cancelled is restarted after retry
+func NewRunner(logger *slog.Logger, tasks []Task, retry time.Duration) *Runner {
+	return &Runner{
+		logger: logger.With("subsystem", "FollowerTasks"),
+		tasks:  tasks,
+		retry:  retry,
+	}
+}
+
+// Start runs all tasks in background. The returned function cancels the tasks and waits for them
+func (r *Runner) Start(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, task := range r.tasks {
+		wg.Add(1)
+		go func(task Task) {
+			defer wg.Done()
+			r.supervise(ctx, task)
+		}(task)
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func (r *Runner) supervise(ctx context.Context, task Task) {
+	logger := r.logger.With("task", task.Name)
+	for {
+		logger.LogAttrs(ctx, slog.LevelInfo, "Starting follower task")
+		err := call(ctx, logger, task)
+		if ctx.Err() != nil {
+			logger.LogAttrs(ctx, slog.LevelInfo, "Follower task cancelled")
+			return
+		}
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "Follower task failed", slog.String("error", err.Error()))
+		} else {
+			logger.LogAttrs(ctx, slog.LevelWarn, "Follower task returned before cancellation")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.retry):
+		}
+	}
+}
+
+// call runs the task and converts its panic into an error
+func call(ctx context.Context, logger *slog.Logger, task Task) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "Follower task panicked", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("follower task %s panicked: %v", task.Name, p)
+		}
+	}()
+	return task.Run(ctx)
+}
+
+// Command creates a task running an external command for as long as the node is a follower.
+// The command is killed when the task is cancelled
+func Command(args []string) (Task, error) {
+	if len(args) == 0 {
+		return Task{}, errors.New("follower command is empty")
+	}
+	return Task{
+		Name: "command",
+		Run: func(ctx context.Context) error {
+			cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("run command %s: %w", args[0], err)
+			}
+			return nil
+		},
+	}, nil
+}
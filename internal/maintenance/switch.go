@@ -0,0 +1,102 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// pollInterval is how often the flag file is checked
+const pollInterval = time.Second
+
+// Switch tells whether the node is in maintenance. It is turned on manually, through a signal or
+// the admin API, or by the existence of the flag file
+type Switch struct {
+	logger *slog.Logger
+	file   string
+
+	mu      sync.Mutex
+	manual  bool
+	fileOn  bool
+	changed chan struct{}
+}
+
+// New creates a switch, file is the path of the flag file or empty to disable it
+func New(logger *slog.Logger, file string) *Switch {
+	return &Switch{
+		logger:  logger.With("subsystem", "Maintenance"),
+		file:    file,
+		changed: make(chan struct{}),
+	}
+}
+
+// Enabled reports whether the node must stay out of the election
+func (s *Switch) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.manual || s.fileOn
+}
+
+// Set turns the manual maintenance on or off, the flag file keeps maintenance on while it exists
+func (s *Switch) Set(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.manual == enabled {
+		return
+	}
+	s.manual = enabled
+	s.logger.Info("Manual maintenance changed", slog.Bool("enabled", enabled))
+	s.notify()
+}
+
+// Changed returns a channel that is closed on the next change of the switch
+func (s *Switch) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Run watches the flag file until the context is done
+func (s *Switch) Run(ctx context.Context) {
+	if s.file == "" {
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		s.checkFile(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Switch) checkFile(ctx context.Context) {
+	_, err := os.Stat(s.file)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error checking maintenance file", slog.String("error", err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fileOn == exists {
+		return
+	}
+	s.fileOn = exists
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Maintenance file changed", slog.String("file", s.file), slog.Bool("enabled", exists))
+	s.notify()
+}
+
+// notify wakes up the waiters, the caller holds the lock
+func (s *Switch) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Format selects how a Record is encoded into the file content
+type Format string
+
+const (
+	// FormatText renders the content template
+	FormatText Format = "text"
+	// FormatJSON writes an indented JSON document
+	FormatJSON Format = "json"
+	// FormatNDJSON writes the record as a single JSON line terminated by a newline
+	FormatNDJSON Format = "ndjson"
+)
+
+// SchemaVersion is bumped whenever fields of jsonRecord change incompatibly
+const SchemaVersion = 1
+
+// ParseFormat validates the format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatNDJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q, expected one of: text, json, ndjson", s)
+	}
+}
+
+// DefaultNameTemplate returns the name template used when none is configured
+func DefaultNameTemplate(format Format) string {
+	switch format {
+	case FormatJSON:
+		return defaultNamePrefix + ".json"
+	case FormatNDJSON:
+		return defaultNamePrefix + ".ndjson"
+	default:
+		return defaultNamePrefix + ".txt"
+	}
+}
+
+// jsonRecord is the wire schema of structured records
+type jsonRecord struct {
+	SchemaVersion int       `json:"schema_version"`
+	Kind          Kind      `json:"kind"`
+	NodeID        string    `json:"node_id"`
+	Hostname      string    `json:"hostname"`
+	Term          int64     `json:"term"`
+	Seq           uint64    `json:"seq"`
+	WallTime      time.Time `json:"wall_time"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	UptimeMs      int64     `json:"uptime_ms"`
+}
+
+func encodeJSON(rec Record, format Format) ([]byte, error) {
+	v := jsonRecord{
+		SchemaVersion: SchemaVersion,
+		Kind:          rec.Kind,
+		NodeID:        rec.NodeID,
+		Hostname:      rec.Hostname,
+		Term:          rec.Term,
+		Seq:           rec.Seq,
+		WallTime:      rec.Time.UTC(),
+		ScheduledTime: rec.Scheduled.UTC(),
+		UptimeMs:      rec.Uptime.Milliseconds(),
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if format == FormatJSON {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode record: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Sink = &LocalSink{}
+
+// staleTempAge is the age after which an unfinished atomic write is considered left by a crashed writer.
+// It is far above the duration of a single write, so a write in progress is never removed
+const staleTempAge = 10 * time.Minute
+
+// LocalSink stores artifacts as files in a local directory
+type LocalSink struct {
+	dir string
+}
+
+// NewLocalSink creates a sink writing into the directory
+func NewLocalSink(dir string) *LocalSink {
+	return &LocalSink{dir: dir}
+}
+
+func (s *LocalSink) String() string {
+	return s.dir
+}
+
+// Put atomically writes the file into the directory
+func (s *LocalSink) Put(_ context.Context, name string, data []byte) error {
+	return WriteFileAtomic(filepath.Join(s.dir, name), data, 0o644)
+}
+
+// List returns files of the directory. Directories and unfinished atomic writes are skipped,
+// the writes older than staleTempAge are removed, so temporary files of crashed writers do not pile up
+func (s *LocalSink) List(_ context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	now := time.Now()
+	objects := make([]Object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed between reading the directory and the stat call
+			continue
+		}
+		if IsTempFile(entry.Name()) {
+			if now.Sub(info.ModTime()) > staleTempAge {
+				err := os.Remove(filepath.Join(s.dir, entry.Name()))
+				if err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return nil, fmt.Errorf("failed to remove stale temporary file %s: %w", entry.Name(), err)
+				}
+			}
+			continue
+		}
+		objects = append(objects, Object{
+			Name:    entry.Name(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return objects, nil
+}
+
+// Delete removes the file from the directory
+func (s *LocalSink) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
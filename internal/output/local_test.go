@@ -0,0 +1,44 @@
+package output
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocalSinkListRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewLocalSink(dir)
+	ctx := context.Background()
+
+	if err := sink.Put(ctx, "leader_1.txt", []byte("Leader active")); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, ".leader_0.txt"+tempMarker+"123")
+	fresh := filepath.Join(dir, ".leader_2.txt"+tempMarker+"456")
+	for _, name := range []string{stale, fresh} {
+		if err := os.WriteFile(name, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := sink.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Name != "leader_1.txt" {
+		t.Errorf("List = %v, want only leader_1.txt", objects)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file was kept: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("temporary file of a write in progress was removed: %v", err)
+	}
+}
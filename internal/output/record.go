@@ -0,0 +1,46 @@
+package output
+
+import "time"
+
+// Kind tells consumers how a record was produced
+type Kind string
+
+const (
+	// KindTick is a record written by the leader on schedule
+	KindTick Kind = "tick"
+	// KindBackfill is a record produced later for an interval that had no leader
+	KindBackfill Kind = "backfill"
+	// KindGap marks an interval that had no leader and was not backfilled
+	KindGap Kind = "gap"
+)
+
+// Record describes a single artifact produced by the leader
+type Record struct {
+	Kind     Kind
+	Term     int64
+	NodeID   string
+	Hostname string
+	Seq      uint64
+	Time     time.Time
+	// Scheduled is the activation of the schedule the record belongs to
+	Scheduled time.Time
+	Uptime    time.Duration
+}
+
+// RFC3339 returns the record time formatted according to RFC3339
+func (r Record) RFC3339() string {
+	return r.Time.UTC().Format(time.RFC3339)
+}
+
+// Unix returns the record time as a unix timestamp
+func (r Record) Unix() int64 {
+	return r.Time.Unix()
+}
+
+// processStart is read through time.Since, which uses the monotonic clock and is not affected by wall clock jumps
+var processStart = time.Now()
+
+// Uptime returns the monotonic time elapsed since the process started
+func Uptime() time.Duration {
+	return time.Since(processStart)
+}
@@ -0,0 +1,175 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var _ Sink = &S3Sink{}
+
+// S3Config describes the location and credentials of an S3-compatible bucket
+type S3Config struct {
+	// Endpoint is the base URL of the storage, e.g. http://minio:9000 or https://s3.eu-west-1.amazonaws.com
+	Endpoint  string
+	Bucket    string
+	Prefix    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Sink stores artifacts as objects of an S3-compatible bucket using path-style requests,
+// which are supported by both AWS and MinIO
+type S3Sink struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Sink validates the configuration and creates the sink
+func NewS3Sink(config S3Config, client *http.Client) (*S3Sink, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is not set")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("s3 endpoint %q must use http or https", config.Endpoint)
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3Sink{
+		config:   config,
+		endpoint: endpoint,
+		client:   client,
+	}, nil
+}
+
+func (s *S3Sink) String() string {
+	return "s3://" + path.Join(s.config.Bucket, s.config.Prefix)
+}
+
+// Put uploads the object. A single PUT is atomic in S3, so readers never observe a partial object
+func (s *S3Sink) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.key(name), nil, data)
+	if err != nil {
+		return fmt.Errorf("put object %s: %w", name, err)
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes the object
+func (s *S3Sink) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.key(name), nil, nil)
+	if err != nil {
+		return fmt.Errorf("delete object %s: %w", name, err)
+	}
+	return resp.Body.Close()
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List returns objects stored directly under the prefix
+func (s *S3Sink) List(ctx context.Context) ([]Object, error) {
+	prefix := ""
+	if s.config.Prefix != "" {
+		prefix = s.config.Prefix + "/"
+	}
+
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("delimiter", "/")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode list objects response: %w", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Name:    strings.TrimPrefix(content.Key, prefix),
+				ModTime: content.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3Sink) key(name string) string {
+	if s.config.Prefix == "" {
+		return name
+	}
+	return s.config.Prefix + "/" + name
+}
+
+// do sends a signed request and returns the response if it has a 2xx status
+func (s *S3Sink) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = path.Join("/", s.endpoint.Path, s.config.Bucket)
+	if key != "" {
+		u.Path += "/" + key
+	}
+	// Send the path exactly as it is canonicalized for the signature
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Amz-Content-Sha256", hashHex(body))
+	signV4(req, s.config.AccessKey, s.config.SecretKey, s.config.Region, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
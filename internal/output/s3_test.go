@@ -0,0 +1,273 @@
+package output
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBucket = "artifacts"
+
+// fakeS3 is an in-process S3 server with path-style Put, Delete and ListObjectsV2
+type fakeS3 struct {
+	t        *testing.T
+	pageSize int
+
+	mu        sync.Mutex
+	objects   map[string]fakeObject
+	clock     time.Time
+	listCalls int
+	// failures maps a request method to the status code returned instead of handling the request
+	failures map[string]int
+}
+
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+func newFakeS3(t *testing.T, pageSize int) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		t:        t,
+		pageSize: pageSize,
+		objects:  make(map[string]fakeObject),
+		clock:    time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC),
+		failures: make(map[string]int),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		f.t.Errorf("%s %s is not signed: %q", r.Method, r.URL, r.Header.Get("Authorization"))
+	}
+	if got, want := r.Header.Get("X-Amz-Content-Sha256"), hashHex(body); got != want {
+		f.t.Errorf("%s %s payload hash %s, want %s", r.Method, r.URL, got, want)
+	}
+	if status, ok := f.failures[r.Method]; ok {
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, "<Error><Code>Injected</Code></Error>")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != testBucket {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		f.clock = f.clock.Add(time.Second)
+		f.objects[key] = fakeObject{data: body, modTime: f.clock}
+	case r.Method == http.MethodDelete && key != "":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		f.listCalls++
+		f.list(w, r)
+	default:
+		http.Error(w, "unsupported request", http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns a page of keys directly under the prefix, the continuation token is the offset of the page
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	var keys []string
+	for key := range f.objects {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if ok && !strings.Contains(rest, query.Get("delimiter")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	offset := 0
+	if token := query.Get("continuation-token"); token != "" {
+		offset, _ = strconv.Atoi(token)
+	}
+	end := min(offset+f.pageSize, len(keys))
+
+	type content struct {
+		Key          string
+		LastModified time.Time
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []content
+	}{IsTruncated: end < len(keys)}
+	if result.IsTruncated {
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	for _, key := range keys[offset:end] {
+		result.Contents = append(result.Contents, content{Key: key, LastModified: f.objects[key].modTime})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newTestS3Sink(t *testing.T, srv *httptest.Server, prefix string) *S3Sink {
+	sink, err := NewS3Sink(S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    testBucket,
+		Prefix:    prefix,
+		AccessKey: "access",
+		SecretKey: "secret",
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink
+}
+
+func names(objects []Object) []string {
+	result := make([]string, 0, len(objects))
+	for _, o := range objects {
+		result = append(result, o.Name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestS3SinkPutListDelete(t *testing.T) {
+	fake, srv := newFakeS3(t, 2)
+	sink := newTestS3Sink(t, srv, "/leader/")
+	ctx := context.Background()
+
+	put := []string{"a.txt", "b.txt", "c d$.txt", "e.txt", "f.txt"}
+	for _, name := range put {
+		if err := sink.Put(ctx, name, []byte("content of "+name)); err != nil {
+			t.Fatalf("Put(%s): %v", name, err)
+		}
+	}
+	// Objects outside of the prefix or in nested directories are not listed
+	fake.objects["other/x.txt"] = fakeObject{}
+	fake.objects["leader/nested/y.txt"] = fakeObject{}
+
+	if got := string(fake.objects["leader/c d$.txt"].data); got != "content of c d$.txt" {
+		t.Errorf("stored content = %q", got)
+	}
+
+	objects, err := sink.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{"a.txt", "b.txt", "c d$.txt", "e.txt", "f.txt"}
+	if got := names(objects); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+	if fake.listCalls != 3 {
+		t.Errorf("List made %d requests, want 3 pages", fake.listCalls)
+	}
+
+	if err := sink.Delete(ctx, "c d$.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	want = []string{"leader/a.txt", "leader/b.txt", "leader/e.txt", "leader/f.txt", "leader/nested/y.txt", "other/x.txt"}
+	if got := fake.keys(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("keys after Delete = %v, want %v", got, want)
+	}
+}
+
+func TestS3SinkPrune(t *testing.T) {
+	fake, srv := newFakeS3(t, 2)
+	sink := newTestS3Sink(t, srv, "")
+	ctx := context.Background()
+
+	// Written in order, so the first ones are the oldest
+	for _, name := range []string{"5", "4", "3", "2", "1"} {
+		if err := sink.Put(ctx, name, nil); err != nil {
+			t.Fatalf("Put(%s): %v", name, err)
+		}
+	}
+
+	removed, err := Prune(ctx, sink, 2)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if got := strings.Join(removed, ","); got != "5,4,3" {
+		t.Errorf("removed = %s, want 5,4,3", got)
+	}
+	if got := strings.Join(fake.keys(), ","); got != "1,2" {
+		t.Errorf("kept = %s, want 1,2", got)
+	}
+
+	removed, err = Prune(ctx, sink, 2)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Prune within capacity = %v, %v, want nothing removed", removed, err)
+	}
+}
+
+func TestS3SinkErrorStatus(t *testing.T) {
+	fake, srv := newFakeS3(t, 2)
+	sink := newTestS3Sink(t, srv, "")
+	ctx := context.Background()
+
+	fake.failures[http.MethodPut] = http.StatusForbidden
+	fake.failures[http.MethodGet] = http.StatusInternalServerError
+	fake.failures[http.MethodDelete] = http.StatusServiceUnavailable
+
+	if err := sink.Put(ctx, "a.txt", []byte("a")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put error = %v, want status 403", err)
+	}
+	if _, err := sink.List(ctx); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("List error = %v, want status 500", err)
+	}
+	if err := sink.Delete(ctx, "a.txt"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Delete error = %v, want status 503", err)
+	}
+	if _, err := Prune(ctx, sink, 0); err == nil {
+		t.Error("Prune succeeded although List fails")
+	}
+	if len(fake.keys()) != 0 {
+		t.Errorf("objects stored despite failures: %v", fake.keys())
+	}
+}
+
+func TestNewS3SinkValidation(t *testing.T) {
+	if _, err := NewS3Sink(S3Config{}, nil); err == nil {
+		t.Error("sink without a bucket was created")
+	}
+	if _, err := NewS3Sink(S3Config{Bucket: "b", Endpoint: "ftp://host"}, nil); err == nil {
+		t.Error("sink with a non-http endpoint was created")
+	}
+	sink, err := NewS3Sink(S3Config{Bucket: "b", Prefix: "/p/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.String(); got != "s3://b/p" {
+		t.Errorf("String = %s, want s3://b/p", got)
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Object describes an artifact stored in a sink
+type Object struct {
+	Name    string
+	ModTime time.Time
+}
+
+// Sink is a storage for the artifacts produced by the leader
+type Sink interface {
+	// Put stores the data under the name, replacing an existing object atomically
+	Put(ctx context.Context, name string, data []byte) error
+	// List returns all complete objects in the sink
+	List(ctx context.Context) ([]Object, error)
+	// Delete removes the object with the name
+	Delete(ctx context.Context, name string) error
+	// String returns the human-readable location of the sink
+	String() string
+}
+
+// Prune ensures that the number of objects in the sink does not exceed the capacity
+// by deleting the oldest ones. It returns names of the deleted objects
+func Prune(ctx context.Context, sink Sink, capacity int) ([]string, error) {
+	objects, err := sink.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	if len(objects) <= capacity {
+		return nil, nil
+	}
+
+	// Sort objects by modification time, name breaks ties of the same second
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].ModTime.Equal(objects[j].ModTime) {
+			return objects[i].Name < objects[j].Name
+		}
+		return objects[i].ModTime.Before(objects[j].ModTime)
+	})
+
+	// Remove oldest objects if exceeding storage capacity
+	excess := len(objects) - capacity
+	removed := make([]string, 0, excess)
+	for i := 0; i < excess; i++ {
+		if err := sink.Delete(ctx, objects[i].Name); err != nil {
+			return removed, fmt.Errorf("failed to remove object %s: %w", objects[i].Name, err)
+		}
+		removed = append(removed, objects[i].Name)
+	}
+	return removed, nil
+}
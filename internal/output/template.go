@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+)
+
+const (
+	// defaultNamePrefix keeps the unix timestamp prefix but adds the term and the sequence number,
+	// so that two ticks within the same second never share a file name. Backfilled and gap records
+	// carry their kind, so they are never mistaken for ticks
+	defaultNamePrefix = "leader_{{.Unix}}_{{.Term}}_{{.Seq}}" + kindSuffix
+	// kindSuffix names the kind of every record but a tick
+	kindSuffix = `{{if ne .Kind "tick"}}_{{.Kind}}{{end}}`
+	// DefaultContentTemplate is the payload written by the leader when no other template is configured
+	DefaultContentTemplate = `Leader active{{if ne .Kind "tick"}} ({{.Kind}}){{end}}`
+)
+
+// Renderer builds file names and file contents from the configured templates
+type Renderer struct {
+	format  Format
+	name    *template.Template
+	content *template.Template
+}
+
+// NewRenderer parses the name and content templates. Both templates are executed against Record,
+// so they can use {{.Term}}, {{.NodeID}}, {{.Seq}}, {{.RFC3339}} and {{.Unix}}.
+// An empty name template falls back to DefaultNameTemplate for the format.
+// The content template is only used by FormatText
+func NewRenderer(format Format, nameTemplate, contentTemplate string) (*Renderer, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate(format)
+	}
+	name, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse file name template: %w", err)
+	}
+	content, err := template.New("content").Option("missingkey=error").Parse(contentTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse file content template: %w", err)
+	}
+	return &Renderer{
+		format:  format,
+		name:    name,
+		content: content,
+	}, nil
+}
+
+// Name renders the file name for the record. The result must be a plain file name without directories
+func (r *Renderer) Name(rec Record) (string, error) {
+	var buf bytes.Buffer
+	if err := r.name.Execute(&buf, rec); err != nil {
+		return "", fmt.Errorf("render file name: %w", err)
+	}
+	name := buf.String()
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return "", fmt.Errorf("render file name: %q is not a valid file name", name)
+	}
+	if IsTempFile(name) {
+		return "", fmt.Errorf("render file name: %q clashes with temporary file naming", name)
+	}
+	return name, nil
+}
+
+// Content renders the file content for the record
+func (r *Renderer) Content(rec Record) ([]byte, error) {
+	if r.format == FormatJSON || r.format == FormatNDJSON {
+		return encodeJSON(rec, r.format)
+	}
+	var buf bytes.Buffer
+	if err := r.content.Execute(&buf, rec); err != nil {
+		return nil, fmt.Errorf("render file content: %w", err)
+	}
+	return buf.Bytes(), nil
+}
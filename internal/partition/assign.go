@@ -0,0 +1,58 @@
+package partition
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Assignment maps every live candidate to the partitions it owns
+type Assignment struct {
+	// Generation increases with every rebalance, workers use it to detect changes
+	Generation uint64              `json:"generation"`
+	Members    map[string][]string `json:"members"`
+}
+
+// Assign distributes partitions across members round-robin. Members are sorted,
+// so every coordinator computes the same assignment for the same membership
+func Assign(partitions, members []string) map[string][]string {
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+
+	result := make(map[string][]string, len(sorted))
+	for _, member := range sorted {
+		result[member] = []string{}
+	}
+	if len(sorted) == 0 {
+		return result
+	}
+	for i, p := range partitions {
+		member := sorted[i%len(sorted)]
+		result[member] = append(result[member], p)
+	}
+	return result
+}
+
+func decode(data []byte) (Assignment, error) {
+	var a Assignment
+	if len(data) == 0 {
+		return a, nil
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Assignment{}, fmt.Errorf("decode assignment: %w", err)
+	}
+	return a, nil
+}
+
+func equal(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for member, pa := range a {
+		pb, ok := b[member]
+		if !ok || !equalPartitions(pa, pb) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package partition
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Coordinator is run by the leader. It watches the candidates of the election
+// and rewrites the assignment whenever the membership changes
+type Coordinator struct {
+	logger         *slog.Logger
+	conn           *zk.Conn
+	electionPath   string
+	assignmentPath string
+	partitions     []string
+	retry          time.Duration
+}
+
+// NewCoordinator creates a coordinator distributing the partitions across children of electionPath
+func NewCoordinator(
+	logger *slog.Logger,
+	conn *zk.Conn,
+	electionPath, assignmentPath string,
+	partitions []string,
+	retry time.Duration,
+) *Coordinator {
+	return &Coordinator{
+		logger:         logger.With("subsystem", "PartitionCoordinator"),
+		conn:           conn,
+		electionPath:   electionPath,
+		assignmentPath: assignmentPath,
+		partitions:     partitions,
+		retry:          retry,
+	}
+}
+
+// Run rebalances partitions until the context is done. ZooKeeper errors are logged and retried
+func (c *Coordinator) Run(ctx context.Context) {
+	for {
+		ch, err := c.rebalance(ctx)
+		if err != nil {
+			c.logger.LogAttrs(ctx, slog.LevelError, "Error rebalancing partitions", slog.String("error", err.Error()))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.retry):
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+	}
+}
+
+// rebalance writes the assignment for the current candidates and returns the watch of the membership
+func (c *Coordinator) rebalance(ctx context.Context) (<-chan zk.Event, error) {
+	members, _, ch, err := c.conn.ChildrenW(c.electionPath)
+	if err != nil {
+		return nil, fmt.Errorf("watch candidates: %w", err)
+	}
+
+	data, stat, err := c.conn.Get(c.assignmentPath)
+	version := int32(-1)
+	switch {
+	case errors.Is(err, zk.ErrNoNode):
+		data = nil
+	case err != nil:
+		return nil, fmt.Errorf("get assignment: %w", err)
+	default:
+		version = stat.Version
+	}
+	current, err := decode(data)
+	if err != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "Overwriting malformed assignment", slog.String("error", err.Error()))
+	}
+
+	members = candidates(members)
+	next := Assign(c.partitions, members)
+	if equal(current.Members, next) {
+		return ch, nil
+	}
+
+	assignment := Assignment{
+		Generation: current.Generation + 1,
+		Members:    next,
+	}
+	payload, err := json.Marshal(assignment)
+	if err != nil {
+		return nil, fmt.Errorf("encode assignment: %w", err)
+	}
+	if version < 0 {
+		_, err = c.conn.Create(c.assignmentPath, payload, 0, zk.WorldACL(zk.PermAll))
+	} else {
+		_, err = c.conn.Set(c.assignmentPath, payload, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write assignment: %w", err)
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "Partitions rebalanced",
+		slog.Uint64("generation", assignment.Generation), slog.Int("members", len(members)))
+	return ch, nil
+}
@@ -0,0 +1,165 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// candidateMarker is contained in the names of election znodes
+const candidateMarker = "guid-n_"
+
+// Task processes the partitions owned by this node until the context is cancelled.
+// It is restarted with the new partitions on every rebalance
+type Task func(ctx context.Context, partitions []string) error
+
+// LogTask is the default task, it only reports the owned partitions
+func LogTask(logger *slog.Logger) Task {
+	return func(ctx context.Context, partitions []string) error {
+		logger.LogAttrs(ctx, slog.LevelInfo, "Processing partitions", slog.String("partitions", strings.Join(partitions, ",")))
+		<-ctx.Done()
+		return nil
+	}
+}
+
+// Worker follows the assignment written by the coordinator and runs the task for the partitions of its member
+type Worker struct {
+	logger         *slog.Logger
+	conn           *zk.Conn
+	assignmentPath string
+	task           Task
+	retry          time.Duration
+}
+
+// NewWorker creates a worker reading the assignment from assignmentPath
+func NewWorker(logger *slog.Logger, conn *zk.Conn, assignmentPath string, task Task, retry time.Duration) *Worker {
+	return &Worker{
+		logger:         logger.With("subsystem", "PartitionWorker"),
+		conn:           conn,
+		assignmentPath: assignmentPath,
+		task:           task,
+		retry:          retry,
+	}
+}
+
+// Run executes the task for partitions assigned to the member until the context is done.
+// The member is the name of the election znode of this node
+func (w *Worker) Run(ctx context.Context, member string) {
+	var (
+		current  []string
+		assigned bool
+		stop     = func() {}
+	)
+	defer func() { stop() }()
+
+	for {
+		partitions, ch, err := w.watch(member)
+		if err != nil {
+			w.logger.LogAttrs(ctx, slog.LevelError, "Error reading assignment", slog.String("error", err.Error()))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.retry):
+			}
+			continue
+		}
+
+		if !assigned || !equalPartitions(current, partitions) {
+			stop()
+			current, assigned = partitions, true
+			w.logger.LogAttrs(ctx, slog.LevelInfo, "Partitions assigned", slog.String("partitions", strings.Join(partitions, ",")))
+			stop = w.start(ctx, partitions)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+	}
+}
+
+// start runs the task in background and returns a function cancelling and awaiting it
+func (w *Worker) start(ctx context.Context, partitions []string) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := w.call(ctx, partitions); err != nil && !errors.Is(err, context.Canceled) {
+			w.logger.LogAttrs(ctx, slog.LevelError, "Partition task failed", slog.String("error", err.Error()))
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// call runs the task and converts its panic into an error
+func (w *Worker) call(ctx context.Context, partitions []string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			w.logger.LogAttrs(ctx, slog.LevelError, "Partition task panicked", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("partition task panicked: %v", p)
+		}
+	}()
+	return w.task(ctx, partitions)
+}
+
+// watch returns the partitions of the member and the watch of the assignment znode
+func (w *Worker) watch(member string) ([]string, <-chan zk.Event, error) {
+	exists, _, ch, err := w.conn.ExistsW(w.assignmentPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("watch assignment: %w", err)
+	}
+	if !exists {
+		return []string{}, ch, nil
+	}
+	data, _, ch, err := w.conn.GetW(w.assignmentPath)
+	if errors.Is(err, zk.ErrNoNode) {
+		return w.watch(member)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("get assignment: %w", err)
+	}
+	assignment, err := decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	partitions := assignment.Members[member]
+	if partitions == nil {
+		partitions = []string{}
+	}
+	return partitions, ch, nil
+}
+
+// candidates filters out znodes that do not belong to the election
+func candidates(children []string) []string {
+	result := make([]string, 0, len(children))
+	for _, child := range children {
+		if strings.Contains(child, candidateMarker) {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+func equalPartitions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package partition
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestWorkerStartRecoversPanic(t *testing.T) {
+	w := NewWorker(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, "/assignment", func(context.Context, []string) error {
+		panic("boom")
+	}, 0)
+
+	if err := w.call(context.Background(), []string{"p1"}); err == nil {
+		t.Error("panicking task reported success")
+	}
+	// The panic must not crash the process when the task runs in background
+	stop := w.start(context.Background(), []string{"p1"})
+	stop()
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "next minute",
+			expr:  "* * * * *",
+			after: time.Date(2024, time.May, 1, 10, 0, 30, 0, time.UTC),
+			want:  time.Date(2024, time.May, 1, 10, 1, 0, 0, time.UTC),
+		},
+		{
+			name:  "strictly after",
+			expr:  "0 2 * * *",
+			after: time.Date(2024, time.May, 1, 2, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "step and list",
+			expr:  "*/20 9,17 * * *",
+			after: time.Date(2024, time.May, 1, 9, 45, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 1, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "names and range",
+			expr:  "0 0 * jun mon-fri",
+			after: time.Date(2024, time.May, 31, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "sunday as 7",
+			expr:  "0 0 * * 7",
+			after: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Both day fields restricted: either of them matches
+			name:  "day of month or day of week",
+			expr:  "0 0 15 * mon",
+			after: time.Date(2024, time.May, 7, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "day of month or day of week, day of month first",
+			expr:  "0 0 15 * mon",
+			after: time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Only one day field restricted: the other one is ignored
+			name:  "day of week only",
+			expr:  "0 0 * * mon",
+			after: time.Date(2024, time.May, 7, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "leap day",
+			expr:  "0 0 29 2 *",
+			after: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "descriptor",
+			expr:  "@monthly",
+			after: time.Date(2024, time.December, 15, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+
+	// 2:30 does not exist on the day clocks move forward, the next one is on the following day
+	c, err := ParseCron("30 2 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2024, time.March, 10, 1, 0, 0, 0, loc))
+	if want := time.Date(2024, time.March, 11, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next over spring forward = %s, want %s", got, want)
+	}
+
+	// An hour matched after 1:00 repeats is not skipped
+	c, err = ParseCron("0 3 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = c.Next(time.Date(2024, time.November, 3, 0, 30, 0, 0, loc))
+	if want := time.Date(2024, time.November, 3, 3, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next over fall back = %s, want %s", got, want)
+	}
+
+	// 1:30 occurs twice when clocks move back, both activations are in order
+	c, err = ParseCron("30 1 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := c.Next(time.Date(2024, time.November, 3, 0, 0, 0, 0, loc))
+	second := c.Next(first)
+	if want := time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC); !first.Equal(want) {
+		t.Errorf("first 1:30 = %s, want %s", first.UTC(), want)
+	}
+	if want := time.Date(2024, time.November, 3, 6, 30, 0, 0, time.UTC); !second.Equal(want) {
+		t.Errorf("second 1:30 = %s, want %s", second.UTC(), want)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := ParseCron(expr, nil); err == nil {
+			t.Errorf("ParseCron(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule computes activation times of periodic work
+type Schedule interface {
+	// Next returns the first activation strictly after the given time
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// epoch is the origin of the interval grid
+var epoch = time.Unix(0, 0)
+
+// Interval activates every period. Activations are aligned to the unix epoch rather than to the moment
+// the leader was elected, so every leader produces ticks on the same grid
+type Interval struct {
+	period time.Duration
+}
+
+// Every creates an interval schedule
+func Every(period time.Duration) (*Interval, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("interval %s must be positive", period)
+	}
+	return &Interval{period: period}, nil
+}
+
+func (i *Interval) Next(after time.Time) time.Time {
+	// time.Truncate would align to the zero time, a different grid for periods not dividing a day
+	elapsed := after.Sub(epoch)
+	n := elapsed / i.period
+	if elapsed < 0 && elapsed%i.period != 0 {
+		n--
+	}
+	return epoch.Add((n + 1) * i.period).In(after.Location())
+}
+
+func (i *Interval) String() string {
+	return "@every " + i.period.String()
+}
+
+// Period returns the interval between activations
+func (i *Interval) Period() time.Duration {
+	return i.period
+}
+
+// Parse creates a schedule from a cron expression or from an "@every <duration>" descriptor.
+// Cron expressions are evaluated in the location
+func Parse(expr string, loc *time.Location) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		period, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("parse %q: %w", expr, err)
+		}
+		interval, err := Every(period)
+		if err != nil {
+			return nil, err
+		}
+		return interval, nil
+	}
+	cron, err := ParseCron(expr, loc)
+	if err != nil {
+		return nil, err
+	}
+	return cron, nil
+}
+
+// MisfirePolicy defines what to do with activations missed while there was no leader
+type MisfirePolicy string
+
+const (
+	// MisfireSkip drops missed activations and waits for the next one
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireOnce runs the latest missed activation once
+	MisfireOnce MisfirePolicy = "once"
+	// MisfireAll runs every missed activation in order
+	MisfireAll MisfirePolicy = "all"
+)
+
+// MaxMisfires limits the number of activations replayed by MisfireAll
+const MaxMisfires = 1000
+
+// ParseMisfirePolicy validates the policy name
+func ParseMisfirePolicy(s string) (MisfirePolicy, error) {
+	switch p := MisfirePolicy(s); p {
+	case MisfireSkip, MisfireOnce, MisfireAll:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown misfire policy %q, expected one of: skip, once, all", s)
+	}
+}
+
+// Missed returns activations in (last, now] that have to be run according to the policy.
+// A zero last time means that the work has never run, so nothing is considered missed.
+// The second result reports that MisfireAll activations were truncated to MaxMisfires
+func Missed(s Schedule, policy MisfirePolicy, last, now time.Time) ([]time.Time, bool) {
+	if last.IsZero() || policy == MisfireSkip {
+		return nil, false
+	}
+
+	var missed []time.Time
+	truncated := false
+	// A zero time means that the schedule has no further activations
+	for at := s.Next(last); !at.IsZero() && !at.After(now); at = s.Next(at) {
+		missed = append(missed, at)
+		if len(missed) > MaxMisfires {
+			missed = missed[1:]
+			truncated = true
+		}
+	}
+
+	if policy == MisfireOnce && len(missed) > 1 {
+		return missed[len(missed)-1:], false
+	}
+	return missed, truncated
+}
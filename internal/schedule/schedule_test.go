@@ -0,0 +1,161 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		period time.Duration
+		after  time.Time
+		want   time.Time
+	}{
+		{
+			name:   "aligned to the minute",
+			period: time.Minute,
+			after:  time.Date(2024, time.May, 1, 10, 0, 30, 0, time.UTC),
+			want:   time.Date(2024, time.May, 1, 10, 1, 0, 0, time.UTC),
+		},
+		{
+			name:   "strictly after",
+			period: time.Minute,
+			after:  time.Date(2024, time.May, 1, 10, 1, 0, 0, time.UTC),
+			want:   time.Date(2024, time.May, 1, 10, 2, 0, 0, time.UTC),
+		},
+		{
+			// 7 seconds do not divide a day, the grid starts at the unix epoch rather than the zero time
+			name:   "period not dividing a day",
+			period: 7 * time.Second,
+			after:  time.Unix(1714557601, 0),
+			want:   time.Unix(1714557607, 0),
+		},
+		{
+			name:   "before the epoch",
+			period: time.Hour,
+			after:  time.Unix(-5400, 0),
+			want:   time.Unix(-3600, 0),
+		},
+		{
+			name:   "on the grid before the epoch",
+			period: time.Hour,
+			after:  time.Unix(-3600, 0),
+			want:   time.Unix(0, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := Every(tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := i.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntervalKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	i, err := Every(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := i.Next(time.Date(2024, time.May, 1, 10, 30, 0, 0, loc)); got.Location() != loc {
+		t.Errorf("Next location = %s, want %s", got.Location(), loc)
+	}
+}
+
+// never is a schedule whose activations ended before the given time
+type never struct {
+	end time.Time
+}
+
+func (n never) Next(after time.Time) time.Time {
+	if !after.Before(n.end) {
+		return time.Time{}
+	}
+	return n.end
+}
+
+func (n never) String() string {
+	return "never"
+}
+
+func TestMissed(t *testing.T) {
+	minute, err := Every(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return base.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	tests := []struct {
+		name      string
+		schedule  Schedule
+		policy    MisfirePolicy
+		last, now time.Time
+		want      []time.Time
+		truncated bool
+	}{
+		{name: "never run", schedule: minute, policy: MisfireAll, now: at(5)},
+		{name: "skip", schedule: minute, policy: MisfireSkip, last: at(0), now: at(5)},
+		{name: "nothing missed", schedule: minute, policy: MisfireAll, last: at(0), now: at(0).Add(59 * time.Second)},
+		{
+			name:     "all",
+			schedule: minute, policy: MisfireAll, last: at(0), now: at(3),
+			want: []time.Time{at(1), at(2), at(3)},
+		},
+		{
+			name:     "once",
+			schedule: minute, policy: MisfireOnce, last: at(0), now: at(3).Add(30 * time.Second),
+			want: []time.Time{at(3)},
+		},
+		{
+			name:     "no further activations",
+			schedule: never{end: at(2)}, policy: MisfireAll, last: at(0), now: at(10),
+			want: []time.Time{at(2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := Missed(tt.schedule, tt.policy, tt.last, tt.now)
+			if truncated != tt.truncated {
+				t.Errorf("truncated = %t, want %t", truncated, tt.truncated)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Missed = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Missed = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMissedTruncated(t *testing.T) {
+	second, err := Every(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+	now := last.Add(2 * MaxMisfires * time.Second)
+
+	missed, truncated := Missed(second, MisfireAll, last, now)
+	if !truncated || len(missed) != MaxMisfires {
+		t.Fatalf("Missed = %d activations, truncated %t, want %d truncated", len(missed), truncated, MaxMisfires)
+	}
+	// The latest activations are kept
+	if !missed[len(missed)-1].Equal(now) {
+		t.Errorf("last missed = %s, want %s", missed[len(missed)-1], now)
+	}
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// maxOutputLog limits how much of the command output is logged
+const maxOutputLog = 4096
+
+// Command returns a job run function that executes an external command.
+// The scheduled time is passed in the ELECTION_JOB_SCHEDULED environment variable in RFC3339 format
+// and the tick sequence number in ELECTION_JOB_SEQ. ELECTION_JOB_PROGRESS_FILE names a file holding
+// the saved progress of the job, the content the command leaves there is saved as the new progress
+func Command(logger *slog.Logger, name string, args []string) (func(ctx context.Context, act Activation) error, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("job %s has an empty command", name)
+	}
+	return func(ctx context.Context, act Activation) (err error) {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Env = append(os.Environ(),
+			"ELECTION_JOB_NAME="+name,
+			"ELECTION_JOB_SCHEDULED="+act.Scheduled.UTC().Format(time.RFC3339),
+			"ELECTION_JOB_SEQ="+strconv.FormatUint(act.Seq, 10),
+		)
+		if act.Progress != nil {
+			file, saved, err := progressFile(name, act.Progress)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(file)
+			// The progress is saved whatever the outcome, a failed batch resumes from the last saved point
+			defer func() {
+				if saveErr := saveProgressFile(file, saved, act.Progress); saveErr != nil && err == nil {
+					err = saveErr
+				}
+			}()
+			cmd.Env = append(cmd.Env, "ELECTION_JOB_PROGRESS_FILE="+file)
+		}
+		out, err := cmd.CombinedOutput()
+		if len(out) > maxOutputLog {
+			out = out[len(out)-maxOutputLog:]
+		}
+		if len(out) > 0 {
+			logger.LogAttrs(ctx, slog.LevelInfo, "Job output", slog.String("job", name), slog.String("output", string(out)))
+		}
+		if err != nil {
+			return fmt.Errorf("run command %s: %w", args[0], err)
+		}
+		return nil
+	}, nil
+}
+
+// progressFile writes the saved progress of the job into a temporary file for the command
+func progressFile(name string, progress *Progress) (string, []byte, error) {
+	saved, err := progress.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := os.CreateTemp("", "election-job-"+name+"-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("create progress file: %w", err)
+	}
+	_, err = f.Write(saved)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", nil, fmt.Errorf("write progress file: %w", err)
+	}
+	return f.Name(), saved, nil
+}
+
+// saveProgressFile saves the content of the progress file when the command changed it
+func saveProgressFile(file string, saved []byte, progress *Progress) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read progress file: %w", err)
+	}
+	if bytes.Equal(data, saved) {
+		return nil
+	}
+	return progress.Save(data)
+}
@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnhealthy aborts the scheduler when a job exceeds its failure policy
+var ErrUnhealthy = errors.New("leader work keeps failing")
+
+// FailurePolicy defines when failing jobs make the leader give up leadership.
+// Zero values disable the corresponding check
+type FailurePolicy struct {
+	// MaxConsecutive is the number of consecutive failed activations of a job
+	MaxConsecutive int
+	// MaxErrorRate is the tolerated fraction of failed activations of a job within Window, in (0, 1]
+	MaxErrorRate float64
+	Window       time.Duration
+	// MinRuns is the number of activations within Window required before the error rate is evaluated
+	MinRuns int
+}
+
+// Validate checks that the policy values are in range
+func (p FailurePolicy) Validate() error {
+	switch {
+	case p.MaxConsecutive < 0:
+		return errors.New("max failures must not be negative")
+	case p.MaxErrorRate < 0 || p.MaxErrorRate > 1:
+		return fmt.Errorf("max error rate %v must be within (0, 1], or 0 to disable", p.MaxErrorRate)
+	case p.MaxErrorRate > 0 && p.Window <= 0:
+		return errors.New("error rate window must be positive")
+	case p.MinRuns < 0:
+		return errors.New("error rate min runs must not be negative")
+	}
+	return nil
+}
+
+// health tracks the outcomes of a single job against the policy
+type health struct {
+	policy FailurePolicy
+
+	mu          sync.Mutex
+	consecutive int
+	outcomes    []outcome
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// observe records the outcome of an activation and returns ErrUnhealthy if the policy is exceeded
+func (h *health) observe(at time.Time, err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.consecutive++
+	} else {
+		h.consecutive = 0
+	}
+	if h.policy.MaxConsecutive > 0 && h.consecutive >= h.policy.MaxConsecutive {
+		return fmt.Errorf("%d consecutive failures: %w", h.consecutive, ErrUnhealthy)
+	}
+
+	if h.policy.MaxErrorRate <= 0 || h.policy.Window <= 0 {
+		return nil
+	}
+	h.outcomes = append(h.outcomes, outcome{at: at, failed: err != nil})
+	cutoff := at.Add(-h.policy.Window)
+	for len(h.outcomes) > 0 && h.outcomes[0].at.Before(cutoff) {
+		h.outcomes = h.outcomes[1:]
+	}
+	if len(h.outcomes) < h.policy.MinRuns || len(h.outcomes) == 0 {
+		return nil
+	}
+	failed := 0
+	for _, o := range h.outcomes {
+		if o.failed {
+			failed++
+		}
+	}
+	rate := float64(failed) / float64(len(h.outcomes))
+	if rate >= h.policy.MaxErrorRate {
+		return fmt.Errorf("error rate %.2f over %d runs within %s: %w", rate, len(h.outcomes), h.policy.Window, ErrUnhealthy)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
+)
+
+// Job is a named unit of work executed by the leader on its own schedule
+type Job struct {
+	Name     string
+	Schedule schedule.Schedule
+	Misfire  schedule.MisfirePolicy
+	// Timeout bounds a single activation, zero means no limit
+	Timeout time.Duration
+	// Concurrency is the maximum number of simultaneously running activations, at least one.
+	// Activations that fire while the limit is reached are skipped
+	Concurrency int
+	// Delivery claims every activation as a globally numbered tick before running it, off by default.
+	// At-least-once delivery requires a concurrency of one and the misfire policy all, unless the job is backfilled
+	Delivery ticks.Mode
+	// Backfilled is set when the job runs the activations missed while there was no leader by itself
+	Backfilled bool
+	// Run performs the work of the activation
+	Run func(ctx context.Context, act Activation) error
+}
+
+// Activation is a single scheduled run of a job
+type Activation struct {
+	Scheduled time.Time
+	// Seq is the global sequence number of the claimed tick, zero when the job does not claim ticks
+	Seq uint64
+	// Replay is set when a tick left unfinished by a failed run is run again
+	Replay bool
+	// Progress is the checkpoint of the job's own progress, shared by all activations of the job
+	Progress *Progress
+}
+
+func (j Job) validate() error {
+	if j.Name == "" || strings.Contains(j.Name, "/") {
+		return fmt.Errorf("invalid job name %q", j.Name)
+	}
+	if j.Schedule == nil {
+		return fmt.Errorf("job %s has no schedule", j.Name)
+	}
+	if j.Run == nil {
+		return fmt.Errorf("job %s has no run function", j.Name)
+	}
+	if _, err := schedule.ParseMisfirePolicy(string(j.Misfire)); err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if _, err := ticks.ParseMode(string(j.Delivery)); err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if j.Delivery == ticks.ModeAtLeastOnce && j.Concurrency > 1 {
+		return fmt.Errorf("job %s: at-least-once delivery requires concurrency of one", j.Name)
+	}
+	// Ticks missed without a leader are never claimed, so any other policy would lose them
+	if j.Delivery == ticks.ModeAtLeastOnce && j.Misfire != schedule.MisfireAll && !j.Backfilled {
+		return fmt.Errorf("job %s: at-least-once delivery requires the misfire policy all", j.Name)
+	}
+	return nil
+}
+
+// Record is the outcome of the last activation of a job, it is kept in a checkpoint,
+// so a new leader knows which activations were missed
+type Record struct {
+	Scheduled time.Time `json:"scheduled"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Error     string    `json:"error,omitempty"`
+	Runs      uint64    `json:"runs"`
+}
+
+type abortError struct {
+	err error
+}
+
+func (e *abortError) Error() string {
+	return e.err.Error()
+}
+
+func (e *abortError) Unwrap() error {
+	return e.err
+}
+
+// Abort wraps a job error that must stop the whole scheduler,
+// e.g. when the job detects that this node is no longer the leader
+func Abort(err error) error {
+	return &abortError{err: err}
+}
+
+// IsAbort reports whether the error was wrapped with Abort
+func IsAbort(err error) bool {
+	var abort *abortError
+	return errors.As(err, &abort)
+}
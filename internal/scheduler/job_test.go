@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
+)
+
+func TestJobValidate(t *testing.T) {
+	every, err := schedule.Every(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := Job{
+		Name:        "report",
+		Schedule:    every,
+		Misfire:     schedule.MisfireSkip,
+		Concurrency: 1,
+		Delivery:    ticks.ModeOff,
+		Run:         func(context.Context, Activation) error { return nil },
+	}
+
+	tests := []struct {
+		name   string
+		modify func(j *Job)
+		ok     bool
+	}{
+		{name: "valid", modify: func(*Job) {}, ok: true},
+		{name: "name with slash", modify: func(j *Job) { j.Name = "a/b" }},
+		{name: "no schedule", modify: func(j *Job) { j.Schedule = nil }},
+		{name: "no run", modify: func(j *Job) { j.Run = nil }},
+		{name: "unknown misfire", modify: func(j *Job) { j.Misfire = "never" }},
+		{name: "unknown delivery", modify: func(j *Job) { j.Delivery = "twice" }},
+		{
+			name: "at-least-once with misfire all",
+			modify: func(j *Job) {
+				j.Delivery, j.Misfire = ticks.ModeAtLeastOnce, schedule.MisfireAll
+			},
+			ok: true,
+		},
+		{
+			name: "at-least-once with misfire skip",
+			modify: func(j *Job) {
+				j.Delivery, j.Misfire = ticks.ModeAtLeastOnce, schedule.MisfireSkip
+			},
+		},
+		{
+			name: "at-least-once with misfire once",
+			modify: func(j *Job) {
+				j.Delivery, j.Misfire = ticks.ModeAtLeastOnce, schedule.MisfireOnce
+			},
+		},
+		{
+			name: "at-least-once backfilled",
+			modify: func(j *Job) {
+				j.Delivery, j.Misfire, j.Backfilled = ticks.ModeAtLeastOnce, schedule.MisfireSkip, true
+			},
+			ok: true,
+		},
+		{
+			name: "at-least-once concurrent",
+			modify: func(j *Job) {
+				j.Delivery, j.Misfire, j.Concurrency = ticks.ModeAtLeastOnce, schedule.MisfireAll, 2
+			},
+		},
+		{
+			name: "at-most-once with misfire skip",
+			modify: func(j *Job) {
+				j.Delivery = ticks.ModeAtMostOnce
+			},
+			ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := valid
+			tt.modify(&job)
+			if err := job.validate(); (err == nil) != tt.ok {
+				t.Errorf("validate() = %v, want ok %t", err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+)
+
+// progressPrefix is the checkpoint name prefix of the progress saved by jobs themselves
+const progressPrefix = "progress-"
+
+// Progress is the checkpoint in which a job keeps its own progress, so a long-running job resumed
+// by a new leader continues where the previous one stopped instead of starting from scratch.
+// Save is a compare-and-set on the version of the last Load or Save, a checkpoint.ErrConflict
+// means that another leader took over and aborts the scheduler when the job returns it
+type Progress struct {
+	store checkpoint.Store
+	task  string
+
+	mu      sync.Mutex
+	version int32
+	loaded  bool
+}
+
+func newProgress(store checkpoint.Store, job string) *Progress {
+	return &Progress{
+		store: store,
+		task:  progressPrefix + job,
+	}
+}
+
+// Load returns the last saved progress of the job, nil if there is none yet
+func (p *Progress) Load() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.load()
+}
+
+func (p *Progress) load() ([]byte, error) {
+	cp, err := p.store.Load(p.task)
+	if err != nil {
+		return nil, fmt.Errorf("load progress %s: %w", p.task, err)
+	}
+	p.version, p.loaded = cp.Version, true
+	return cp.Data, nil
+}
+
+// Save replaces the progress of the job
+func (p *Progress) Save(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded {
+		if _, err := p.load(); err != nil {
+			return err
+		}
+	}
+	version, err := p.store.Save(p.task, data, p.version)
+	if err != nil {
+		return fmt.Errorf("save progress %s: %w", p.task, err)
+	}
+	p.version = version
+	return nil
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+)
+
+// memStore is a checkpoint.Store in memory with the same versioning as the ZooKeeper one
+type memStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]checkpoint.Checkpoint
+}
+
+func newMemStore() *memStore {
+	return &memStore{checkpoints: make(map[string]checkpoint.Checkpoint)}
+}
+
+func (s *memStore) Load(task string) (checkpoint.Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.checkpoints[task]
+	if !ok {
+		return checkpoint.Checkpoint{Version: checkpoint.NoVersion}, nil
+	}
+	return cp, nil
+}
+
+func (s *memStore) Save(task string, data []byte, version int32) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.checkpoints[task]
+	if !ok {
+		current.Version = checkpoint.NoVersion
+	}
+	if current.Version != version {
+		return 0, checkpoint.ErrConflict
+	}
+	s.checkpoints[task] = checkpoint.Checkpoint{Data: data, Version: version + 1}
+	return version + 1, nil
+}
+
+func TestProgressSaveLoad(t *testing.T) {
+	store := newMemStore()
+	p := newProgress(store, "batch")
+
+	data, err := p.Load()
+	if err != nil || data != nil {
+		t.Fatalf("Load of a new job = %q, %v, want nothing", data, err)
+	}
+	for _, step := range []string{"1", "2"} {
+		if err := p.Save([]byte(step)); err != nil {
+			t.Fatalf("Save(%s): %v", step, err)
+		}
+	}
+
+	// A new leader resumes from the saved progress
+	resumed := newProgress(store, "batch")
+	data, err = resumed.Load()
+	if err != nil || string(data) != "2" {
+		t.Fatalf("resumed Load = %q, %v, want 2", data, err)
+	}
+	if err := resumed.Save([]byte("3")); err != nil {
+		t.Fatalf("resumed Save: %v", err)
+	}
+
+	// The old leader must not overwrite the progress of the new one
+	if err := p.Save([]byte("stale")); !errors.Is(err, checkpoint.ErrConflict) {
+		t.Errorf("stale Save error = %v, want ErrConflict", err)
+	}
+	if cp, _ := store.Load(progressPrefix + "batch"); string(cp.Data) != "3" {
+		t.Errorf("saved progress = %q, want 3", cp.Data)
+	}
+}
+
+func TestCommandProgressFile(t *testing.T) {
+	store := newMemStore()
+	p := newProgress(store, "batch")
+	if err := p.Save([]byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	run, err := Command(slog.New(slog.NewTextHandler(io.Discard, nil)), "batch",
+		[]string{"sh", "-c", `read -r n < "$ELECTION_JOB_PROGRESS_FILE"; echo $((n + 1)) > "$ELECTION_JOB_PROGRESS_FILE"; exit 1`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The progress is kept even though the command failed
+	if err := run(context.Background(), Activation{Scheduled: time.Now(), Progress: p}); err == nil {
+		t.Error("failed command reported success")
+	}
+	if data, err := p.Load(); err != nil || string(data) != "2\n" {
+		t.Errorf("progress = %q, %v, want 2", data, err)
+	}
+}
@@ -0,0 +1,362 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
+)
+
+// recordPrefix is the checkpoint name prefix of job records
+const recordPrefix = "job-"
+
+// Scheduler runs a set of jobs for the duration of a single leadership
+type Scheduler struct {
+	logger      *slog.Logger
+	checkpoints checkpoint.Store
+	policy      FailurePolicy
+	jobs        []Job
+
+	// stop is closed by Stop, jobs start no new activations afterwards
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New validates the jobs and creates a scheduler. Job records are kept in the checkpoint store.
+// A job exceeding the failure policy aborts the scheduler with ErrUnhealthy
+func New(logger *slog.Logger, checkpoints checkpoint.Store, policy FailurePolicy, jobs []Job) (*Scheduler, error) {
+	names := make(map[string]struct{}, len(jobs))
+	for _, job := range jobs {
+		if err := job.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := names[job.Name]; ok {
+			return nil, fmt.Errorf("duplicate job name %s", job.Name)
+		}
+		names[job.Name] = struct{}{}
+	}
+	return &Scheduler{
+		logger:      logger.With("subsystem", "Scheduler"),
+		checkpoints: checkpoints,
+		policy:      policy,
+		jobs:        jobs,
+		stop:        make(chan struct{}),
+	}, nil
+}
+
+// Stop makes the jobs start no new activations, Run returns once the in-flight ones finish.
+// It is used to drain the leader work, cancelling the context of Run still cancels in-flight activations
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Run starts all jobs and blocks until the context is done, the scheduler is stopped or a job aborts,
+// also after the schedules of all jobs are exhausted.
+// In-flight activations are awaited before returning, they are cancelled unless the scheduler is stopped.
+// The returned error is the abort cause or nil
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			r := &jobRunner{
+				Scheduler: s,
+				job:       job,
+				logger:    s.logger.With("job", job.Name),
+				abort:     cancel,
+				health:    &health{policy: s.policy},
+				progress:  newProgress(s.checkpoints, job.Name),
+			}
+			if job.Delivery != "" && job.Delivery != ticks.ModeOff {
+				r.claims = ticks.NewClaimer(s.checkpoints, job.Name, job.Delivery)
+			}
+			r.run(ctx)
+		}(job)
+	}
+	wg.Wait()
+
+	if cause := context.Cause(ctx); IsAbort(cause) {
+		return cause
+	}
+	// A node whose schedules ended keeps the leadership, so that no other node takes the work over
+	select {
+	case <-ctx.Done():
+	case <-s.stop:
+	default:
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Schedules of all jobs are exhausted, holding leadership without work")
+		select {
+		case <-ctx.Done():
+		case <-s.stop:
+		}
+	}
+	return nil
+}
+
+// jobRunner drives the activations of a single job
+type jobRunner struct {
+	*Scheduler
+	job    Job
+	logger *slog.Logger
+	abort  context.CancelCauseFunc
+	health *health
+	// claims is nil when ticks of the job are not claimed
+	claims   *ticks.Claimer
+	progress *Progress
+
+	// mu guards the record, activations of the same job may finish concurrently
+	mu      sync.Mutex
+	record  Record
+	version int32
+}
+
+func (r *jobRunner) run(ctx context.Context) {
+	if err := r.load(); err != nil {
+		r.abort(Abort(err))
+		return
+	}
+	if r.claims != nil {
+		if err := r.claims.Load(); err != nil {
+			r.abort(Abort(err))
+			return
+		}
+	}
+
+	limit := r.job.Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	activate := func(scheduled time.Time, wait bool, run func(ctx context.Context)) {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		if wait {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			}
+		} else {
+			select {
+			case slots <- struct{}{}:
+			default:
+				r.logger.LogAttrs(ctx, slog.LevelWarn, "Concurrency limit reached, skipping activation",
+					slog.Time("scheduled", scheduled), slog.Int("limit", limit))
+				return
+			}
+		}
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			defer func() { <-slots }()
+			run(ctx)
+		}()
+	}
+
+	// Replay the tick the previous leader claimed but did not finish
+	if r.claims != nil {
+		if pending, ok := r.claims.Pending(); ok {
+			activate(pending.Scheduled, true, func(ctx context.Context) { r.replay(ctx) })
+		}
+	}
+
+	// Catch up with activations missed while there was no leader
+	missed, truncated := schedule.Missed(r.job.Schedule, r.job.Misfire, r.record.Scheduled, time.Now())
+	if truncated {
+		r.logger.LogAttrs(ctx, slog.LevelWarn, "Too many missed activations, replaying only the latest", slog.Int("count", len(missed)))
+	}
+	for _, at := range missed {
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "Running missed activation", slog.Time("scheduled", at))
+		activate(at, true, func(ctx context.Context) { r.execute(ctx, at) })
+	}
+
+	for {
+		next := r.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "Schedule has no further activations", slog.String("schedule", r.job.Schedule.String()))
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			activate(next, false, func(ctx context.Context) { r.execute(ctx, next) })
+		}
+	}
+}
+
+// execute claims the tick of the activation, if the job requires it, and runs it
+func (r *jobRunner) execute(ctx context.Context, scheduled time.Time) {
+	act := Activation{Scheduled: scheduled}
+	if r.claims != nil {
+		// A tick left unfinished by a failure is replayed before new ticks are claimed
+		if !r.replay(ctx) {
+			return
+		}
+		tick, ok, err := r.claims.Claim(scheduled)
+		if err != nil {
+			r.claimFailed(ctx, err)
+			return
+		}
+		if !ok {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "Tick is already claimed, skipping", slog.Time("scheduled", scheduled))
+			return
+		}
+		act.Seq = tick.Seq
+	}
+	r.attempt(ctx, act)
+}
+
+// replay runs the pending tick of at-least-once delivery and reports whether nothing is pending anymore
+func (r *jobRunner) replay(ctx context.Context) bool {
+	pending, ok := r.claims.Pending()
+	if !ok {
+		return true
+	}
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "Replaying unfinished tick", slog.Uint64("seq", pending.Seq), slog.Time("scheduled", pending.Scheduled))
+	return r.attempt(ctx, Activation{
+		Scheduled: pending.Scheduled,
+		Seq:       pending.Seq,
+		Replay:    true,
+	})
+}
+
+func (r *jobRunner) claimFailed(ctx context.Context, err error) {
+	if errors.Is(err, checkpoint.ErrConflict) {
+		r.abort(Abort(err))
+		return
+	}
+	r.logger.LogAttrs(ctx, slog.LevelError, "Error claiming tick", slog.String("error", err.Error()))
+}
+
+// attempt runs a single activation, records its outcome and reports whether it succeeded
+func (r *jobRunner) attempt(ctx context.Context, act Activation) bool {
+	if r.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.job.Timeout)
+		defer cancel()
+	}
+
+	act.Progress = r.progress
+	started := time.Now()
+	err := r.call(ctx, act)
+	finished := time.Now()
+
+	if err != nil {
+		r.logger.LogAttrs(ctx, slog.LevelError, "Job failed", slog.Time("scheduled", act.Scheduled),
+			slog.Duration("duration", finished.Sub(started)), slog.String("error", err.Error()))
+	} else {
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "Job finished", slog.Time("scheduled", act.Scheduled),
+			slog.Duration("duration", finished.Sub(started)))
+	}
+	// A conflict on a checkpoint means that another node is the leader now
+	if errors.Is(err, checkpoint.ErrConflict) && !IsAbort(err) {
+		err = Abort(err)
+	}
+	if IsAbort(err) {
+		r.abort(err)
+		return false
+	}
+	// Cancellation on leadership loss is not a failure of the job
+	if ctx.Err() == nil || !errors.Is(err, context.Canceled) {
+		if unhealthy := r.health.observe(finished, err); unhealthy != nil {
+			r.abort(Abort(fmt.Errorf("job %s: %w", r.job.Name, unhealthy)))
+			return false
+		}
+	}
+
+	if err == nil && r.claims != nil {
+		if err := r.claims.Complete(ticks.Tick{Seq: act.Seq, Scheduled: act.Scheduled}); err != nil {
+			r.claimFailed(ctx, err)
+			return false
+		}
+	}
+
+	if err := r.save(act.Scheduled, started, finished, err); err != nil {
+		if errors.Is(err, checkpoint.ErrConflict) {
+			r.abort(Abort(err))
+			return false
+		}
+		r.logger.LogAttrs(ctx, slog.LevelError, "Error saving job record", slog.String("error", err.Error()))
+	}
+	return err == nil
+}
+
+// call runs the job and converts its panic into an error, so a broken job cannot crash the node
+func (r *jobRunner) call(ctx context.Context, act Activation) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.LogAttrs(ctx, slog.LevelError, "Job panicked", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("job %s panicked: %v", r.job.Name, p)
+		}
+	}()
+	return r.job.Run(ctx, act)
+}
+
+func (r *jobRunner) load() error {
+	cp, err := r.checkpoints.Load(recordPrefix + r.job.Name)
+	if err != nil {
+		return fmt.Errorf("load record of job %s: %w", r.job.Name, err)
+	}
+	r.version = cp.Version
+	if cp.Version == checkpoint.NoVersion {
+		return nil
+	}
+	if err := json.Unmarshal(cp.Data, &r.record); err != nil {
+		return fmt.Errorf("decode record of job %s: %w", r.job.Name, err)
+	}
+	return nil
+}
+
+func (r *jobRunner) save(scheduled, started, finished time.Time, runErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record := r.record
+	// Concurrent activations may finish out of order, the record tracks the latest scheduled one
+	if scheduled.After(record.Scheduled) {
+		record.Scheduled = scheduled
+	}
+	record.Started, record.Finished = started, finished
+	record.Error = ""
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	record.Runs++
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode record of job %s: %w", r.job.Name, err)
+	}
+	version, err := r.checkpoints.Save(recordPrefix+r.job.Name, data, r.version)
+	if err != nil {
+		return err
+	}
+	r.record, r.version = record, version
+	return nil
+}
@@ -0,0 +1,72 @@
+package termination
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reason tells why the node stopped
+type Reason string
+
+const (
+	// Graceful is a requested shutdown, e.g. SIGTERM
+	Graceful Reason = "graceful"
+	// FailoverExhausted means ZooKeeper did not come back within the reconnect attempts
+	FailoverExhausted Reason = "failover-exhausted"
+	// Configuration means the node cannot start with the given settings
+	Configuration Reason = "configuration"
+	// Auth means ZooKeeper rejected the credentials or permissions of the node
+	Auth Reason = "auth"
+	// Unexpected covers all other errors
+	Unexpected Reason = "unexpected"
+)
+
+// ExitCode maps the reason to the exit code of the process
+func (r Reason) ExitCode() int {
+	switch r {
+	case Graceful:
+		return 0
+	case Configuration:
+		return 2
+	case FailoverExhausted:
+		return 3
+	case Auth:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// Error carries the termination reason of the error that stopped the node
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+// New wraps the error with the reason
+func New(reason Reason, err error) *Error {
+	return &Error{Reason: reason, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return string(e.Reason)
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ReasonOf returns the termination reason of the error, nil is a graceful stop
+func ReasonOf(err error) Reason {
+	if err == nil {
+		return Graceful
+	}
+	var terr *Error
+	if errors.As(err, &terr) {
+		return terr.Reason
+	}
+	return Unexpected
+}
@@ -0,0 +1,159 @@
+package ticks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+)
+
+// Mode defines the delivery guarantee of scheduled ticks across leader changes
+type Mode string
+
+const (
+	// ModeOff runs ticks without claiming them
+	ModeOff Mode = "off"
+	// ModeAtMostOnce claims a tick before running it and never runs it again, even if the work failed
+	ModeAtMostOnce Mode = "at-most-once"
+	// ModeAtLeastOnce claims a tick before running it and replays it until the work succeeds,
+	// including by the next leader if this one fails in the middle
+	ModeAtLeastOnce Mode = "at-least-once"
+)
+
+// ParseMode validates the mode name
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeOff, ModeAtMostOnce, ModeAtLeastOnce:
+		return m, nil
+	case "":
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("unknown delivery mode %q, expected one of: off, at-most-once, at-least-once", s)
+	}
+}
+
+// checkpointPrefix is the checkpoint name prefix of claims
+const checkpointPrefix = "ticks-"
+
+// Tick is a claimed activation with its global sequence number
+type Tick struct {
+	Seq       uint64
+	Scheduled time.Time
+}
+
+// claim is the checkpointed state of the last claimed tick
+type claim struct {
+	Seq       uint64    `json:"seq"`
+	Scheduled time.Time `json:"scheduled"`
+	Done      bool      `json:"done"`
+}
+
+// Claimer assigns global sequence numbers to ticks of a job. Claims are written with compare-and-set,
+// so two leaders never claim the same tick, and a claim conflict means that this node lost leadership
+type Claimer struct {
+	store checkpoint.Store
+	name  string
+	mode  Mode
+
+	mu      sync.Mutex
+	last    claim
+	version int32
+}
+
+// NewClaimer creates a claimer of the named job
+func NewClaimer(store checkpoint.Store, name string, mode Mode) *Claimer {
+	return &Claimer{
+		store:   store,
+		name:    checkpointPrefix + name,
+		mode:    mode,
+		version: checkpoint.NoVersion,
+	}
+}
+
+// Mode returns the delivery guarantee of the claimer
+func (c *Claimer) Mode() Mode {
+	return c.mode
+}
+
+// Load reads the last claim, it must be called at the start of every leadership
+func (c *Claimer) Load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cp, err := c.store.Load(c.name)
+	if err != nil {
+		return fmt.Errorf("load claim %s: %w", c.name, err)
+	}
+	c.last, c.version = claim{}, cp.Version
+	if cp.Version == checkpoint.NoVersion {
+		return nil
+	}
+	if err := json.Unmarshal(cp.Data, &c.last); err != nil {
+		return fmt.Errorf("decode claim %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// Pending returns the tick that was claimed but not completed in ModeAtLeastOnce
+func (c *Claimer) Pending() (Tick, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.mode != ModeAtLeastOnce || c.last.Seq == 0 || c.last.Done {
+		return Tick{}, false
+	}
+	return Tick{Seq: c.last.Seq, Scheduled: c.last.Scheduled}, true
+}
+
+// Claim reserves the tick scheduled at the given time. It returns false if the tick,
+// or a later one, has already been claimed, or if a pending tick has to be replayed first
+func (c *Claimer) Claim(scheduled time.Time) (Tick, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !scheduled.After(c.last.Scheduled) {
+		return Tick{}, false, nil
+	}
+	if c.mode == ModeAtLeastOnce && c.last.Seq > 0 && !c.last.Done {
+		return Tick{}, false, nil
+	}
+
+	next := claim{
+		Seq:       c.last.Seq + 1,
+		Scheduled: scheduled,
+		// At most once: the tick is consumed by the claim itself
+		Done: c.mode == ModeAtMostOnce,
+	}
+	if err := c.save(next); err != nil {
+		return Tick{}, false, err
+	}
+	return Tick{Seq: next.Seq, Scheduled: next.Scheduled}, true, nil
+}
+
+// Complete marks the tick as done, so it is not replayed
+func (c *Claimer) Complete(tick Tick) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last.Seq != tick.Seq || c.last.Done {
+		return nil
+	}
+	done := c.last
+	done.Done = true
+	return c.save(done)
+}
+
+func (c *Claimer) save(next claim) error {
+	data, err := json.Marshal(next)
+	if err != nil {
+		return fmt.Errorf("encode claim %s: %w", c.name, err)
+	}
+	version, err := c.store.Save(c.name, data, c.version)
+	if err != nil {
+		return fmt.Errorf("save claim %s: %w", c.name, err)
+	}
+	c.last, c.version = next, version
+	return nil
+}
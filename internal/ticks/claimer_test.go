@@ -0,0 +1,161 @@
+package ticks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+)
+
+// memStore is a checkpoint.Store in memory with the same versioning as the ZooKeeper one
+type memStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]checkpoint.Checkpoint
+}
+
+func newMemStore() *memStore {
+	return &memStore{checkpoints: make(map[string]checkpoint.Checkpoint)}
+}
+
+func (s *memStore) Load(task string) (checkpoint.Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.checkpoints[task]
+	if !ok {
+		return checkpoint.Checkpoint{Version: checkpoint.NoVersion}, nil
+	}
+	return cp, nil
+}
+
+func (s *memStore) Save(task string, data []byte, version int32) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.checkpoints[task]
+	if !ok {
+		current.Version = checkpoint.NoVersion
+	}
+	if current.Version != version {
+		return 0, checkpoint.ErrConflict
+	}
+	s.checkpoints[task] = checkpoint.Checkpoint{Data: data, Version: version + 1}
+	return version + 1, nil
+}
+
+var base = time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+// leader creates the claimer of a newly elected leader
+func leader(t *testing.T, store checkpoint.Store, mode Mode) *Claimer {
+	t.Helper()
+	c := NewClaimer(store, "job", mode)
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return c
+}
+
+func mustClaim(t *testing.T, c *Claimer, scheduled time.Time) (Tick, bool) {
+	t.Helper()
+	tick, ok, err := c.Claim(scheduled)
+	if err != nil {
+		t.Fatalf("Claim(%s): %v", scheduled, err)
+	}
+	return tick, ok
+}
+
+func TestClaimerAtLeastOnceAcrossLeaders(t *testing.T) {
+	store := newMemStore()
+	first := leader(t, store, ModeAtLeastOnce)
+
+	tick, ok := mustClaim(t, first, at(1))
+	if !ok || tick.Seq != 1 {
+		t.Fatalf("first claim = %+v, %t, want seq 1", tick, ok)
+	}
+	if err := first.Complete(tick); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	tick, ok = mustClaim(t, first, at(2))
+	if !ok || tick.Seq != 2 {
+		t.Fatalf("second claim = %+v, %t, want seq 2", tick, ok)
+	}
+	// The first leader fails before completing the tick, the next leader finds it pending
+	second := leader(t, store, ModeAtLeastOnce)
+	pending, ok := second.Pending()
+	if !ok || pending.Seq != 2 || !pending.Scheduled.Equal(at(2)) {
+		t.Fatalf("Pending = %+v, %t, want seq 2 at %s", pending, ok, at(2))
+	}
+	// No new tick is claimed before the pending one is replayed
+	if _, ok := mustClaim(t, second, at(3)); ok {
+		t.Error("claimed a new tick while one is pending")
+	}
+	if err := second.Complete(pending); err != nil {
+		t.Fatalf("Complete pending: %v", err)
+	}
+	if _, ok := second.Pending(); ok {
+		t.Error("completed tick is still pending")
+	}
+	tick, ok = mustClaim(t, second, at(3))
+	if !ok || tick.Seq != 3 {
+		t.Fatalf("claim after replay = %+v, %t, want seq 3", tick, ok)
+	}
+
+	// The deposed leader can no longer claim or complete ticks
+	if _, ok, err := first.Claim(at(4)); ok {
+		t.Errorf("old leader claimed a tick, error %v", err)
+	}
+	if err := first.Complete(Tick{Seq: 2, Scheduled: at(2)}); !errors.Is(err, checkpoint.ErrConflict) {
+		t.Errorf("Complete by the old leader = %v, want ErrConflict", err)
+	}
+}
+
+func TestClaimerAtMostOnce(t *testing.T) {
+	store := newMemStore()
+	first := leader(t, store, ModeAtMostOnce)
+
+	tick, ok := mustClaim(t, first, at(1))
+	if !ok || tick.Seq != 1 {
+		t.Fatalf("claim = %+v, %t, want seq 1", tick, ok)
+	}
+	// The tick is consumed by the claim, the next leader never replays it
+	second := leader(t, store, ModeAtMostOnce)
+	if pending, ok := second.Pending(); ok {
+		t.Errorf("Pending = %+v in at-most-once mode", pending)
+	}
+	if _, ok := mustClaim(t, second, at(1)); ok {
+		t.Error("claimed the same tick twice")
+	}
+	if tick, ok := mustClaim(t, second, at(2)); !ok || tick.Seq != 2 {
+		t.Errorf("claim by the next leader = %+v, %t, want seq 2", tick, ok)
+	}
+}
+
+func TestClaimerRejectsOldTicks(t *testing.T) {
+	c := leader(t, newMemStore(), ModeAtLeastOnce)
+	tick, _ := mustClaim(t, c, at(5))
+	if err := c.Complete(tick); err != nil {
+		t.Fatal(err)
+	}
+	for _, scheduled := range []time.Time{at(5), at(4)} {
+		if _, ok := mustClaim(t, c, scheduled); ok {
+			t.Errorf("claimed tick at %s after the one at %s", scheduled, at(5))
+		}
+	}
+}
+
+func TestClaimersRacingForTheSameTick(t *testing.T) {
+	store := newMemStore()
+	first := leader(t, store, ModeAtMostOnce)
+	second := leader(t, store, ModeAtMostOnce)
+
+	if _, ok := mustClaim(t, first, at(1)); !ok {
+		t.Fatal("first claim failed")
+	}
+	if _, _, err := second.Claim(at(1)); !errors.Is(err, checkpoint.ErrConflict) {
+		t.Errorf("concurrent claim = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,67 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edge identifies a transition between two states
+type Edge struct {
+	From string
+	To   string
+}
+
+// Mermaid renders the transitions as a Mermaid state diagram.
+// Counts of taken transitions are appended to the labels when counts is not nil
+func Mermaid(transitions []Transition, counts map[Edge]int) string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n\n")
+	for _, t := range transitions {
+		label := edgeLabel(t, counts)
+		if label == "" {
+			fmt.Fprintf(&b, "%s --> %s\n", t.From, t.To)
+			continue
+		}
+		fmt.Fprintf(&b, "%s --> %s : %s\n", t.From, t.To, label)
+	}
+	return b.String()
+}
+
+// DOT renders the transitions as a Graphviz digraph.
+// Counts of taken transitions are appended to the labels when counts is not nil
+func DOT(transitions []Transition, counts map[Edge]int) string {
+	var b strings.Builder
+	b.WriteString("digraph election {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+	fmt.Fprintf(&b, "\t%q [shape=point, label=\"\"];\n", dotNode(Terminal, true))
+	fmt.Fprintf(&b, "\t%q [shape=doublecircle, label=\"\", width=0.2];\n", dotNode(Terminal, false))
+	for _, t := range transitions {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", dotNode(t.From, true), dotNode(t.To, false), edgeLabel(t, counts))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotNode splits the terminal pseudo state into distinct start and end nodes, as Mermaid does
+func dotNode(state string, from bool) string {
+	if state != Terminal {
+		return state
+	}
+	if from {
+		return "start"
+	}
+	return "end"
+}
+
+func edgeLabel(t Transition, counts map[Edge]int) string {
+	label := t.Description
+	if counts == nil {
+		return label
+	}
+	count := fmt.Sprintf("%d×", counts[Edge{From: t.From, To: t.To}])
+	if label == "" {
+		return count
+	}
+	return label + " (" + count + ")"
+}
@@ -0,0 +1,61 @@
+package run
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Reason tells why the runner moved from one state to another
+type Reason string
+
+const (
+	// ReasonStarted is the reason of the transition into the first state
+	ReasonStarted Reason = "started"
+	// ReasonCompleted is the reason of a transition returned by the state itself
+	ReasonCompleted Reason = "completed"
+	// ReasonCancelled is the reason of the transition into Stopping after the context is done
+	ReasonCancelled Reason = "cancelled"
+	// ReasonWatchdog is the reason of the transition out of a state interrupted by the watchdog
+	ReasonWatchdog Reason = "watchdog"
+	// ReasonFailed is the reason of the final transition after a state returned an error
+	ReasonFailed Reason = "failed"
+)
+
+// Observer receives the lifecycle events of states. State names are the ones of the transition table,
+// Terminal stands for the start and the end of the machine.
+// Observers are called synchronously from the runner goroutine and must not block
+type Observer interface {
+	// OnEnter is called before the state runs
+	OnEnter(ctx context.Context, state string)
+	// OnExit is called after the state has run for the duration, err is the error it returned
+	OnExit(ctx context.Context, state string, duration time.Duration, err error)
+	// OnTransition is called for every validated transition, duration is the time spent in the from state
+	OnTransition(ctx context.Context, from, to string, reason Reason, duration time.Duration)
+}
+
+// NopObserver ignores all events, embed it to implement only a part of Observer
+type NopObserver struct{}
+
+func (NopObserver) OnEnter(context.Context, string) {}
+
+func (NopObserver) OnExit(context.Context, string, time.Duration, error) {}
+
+func (NopObserver) OnTransition(context.Context, string, string, Reason, time.Duration) {}
+
+var _ Observer = &LogObserver{}
+
+// LogObserver writes every transition to the log
+type LogObserver struct {
+	NopObserver
+	logger *slog.Logger
+}
+
+func NewLogObserver(logger *slog.Logger) *LogObserver {
+	return &LogObserver{logger: logger.With("subsystem", "Transitions")}
+}
+
+func (o *LogObserver) OnTransition(ctx context.Context, from, to string, reason Reason, duration time.Duration) {
+	o.logger.LogAttrs(ctx, slog.LevelInfo, "State transition", slog.String("from", from), slog.String("to", to),
+		slog.String("reason", string(reason)), slog.Duration("duration", duration))
+}
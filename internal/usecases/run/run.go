@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
@@ -23,28 +24,114 @@ func NewLoopRunner(logger *slog.Logger, factory factory.StateFactory) *LoopRunne
 	}
 }
 
+// LoopRunner runs states one after another and rejects transitions missing in the Transitions table
+// with a *TransitionError
 type LoopRunner struct {
-	logger  *slog.Logger
-	factory factory.StateFactory
+	logger    *slog.Logger
+	factory   factory.StateFactory
+	observers []Observer
+	watchdog  Watchdog
+}
+
+// Observe subscribes the observer to state events, it must be called before Run
+func (r *LoopRunner) Observe(observer Observer) {
+	r.observers = append(r.observers, observer)
+}
+
+// SetWatchdog limits the time spent in states, it must be called before Run
+func (r *LoopRunner) SetWatchdog(watchdog Watchdog) {
+	r.watchdog = watchdog
 }
 
 func (r *LoopRunner) Run(ctx context.Context, state states.AutomataState) error {
+	if err := r.transition(ctx, nil, state, ReasonStarted, 0); err != nil {
+		return err
+	}
 	for state != nil {
-		select {
-		case <-ctx.Done():
+		// States on the way out are run to the end, any other state is replaced with Stopping
+		if ctx.Err() != nil && !shuttingDown(state) {
 			r.logger.LogAttrs(ctx, slog.LevelInfo, "Context cancelled, transitioning to stopping state")
-			stoppingState, _ := r.factory.GetStoppingState()
-			_, err := stoppingState.Run(ctx)
-			return err
-		default:
-			r.logger.LogAttrs(ctx, slog.LevelInfo, "start running state", slog.String("state", state.String()))
-			var err error
-			state, err = state.Run(ctx)
+			stoppingState, err := r.factory.GetStoppingState()
 			if err != nil {
-				return fmt.Errorf("state %s run: %w", state.String(), err)
+				return fmt.Errorf("get stopping state: %w", err)
 			}
+			if err := r.transition(ctx, state, stoppingState, ReasonCancelled, 0); err != nil {
+				return err
+			}
+			state = stoppingState
+		}
+		next, err := r.step(ctx, state)
+		if err != nil {
+			return err
 		}
+		state = next
 	}
 	r.logger.LogAttrs(ctx, slog.LevelInfo, "no new state, finish")
 	return nil
 }
+
+// shuttingDown reports whether the state belongs to the shutdown path, which runs after the context is cancelled
+func shuttingDown(state states.AutomataState) bool {
+	switch state.String() {
+	case states.Draining, states.Stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// step runs the state, notifies observers and validates the transition into the returned state
+func (r *LoopRunner) step(ctx context.Context, state states.AutomataState) (states.AutomataState, error) {
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "start running state", slog.String("state", state.String()))
+	for _, o := range r.observers {
+		o.OnEnter(ctx, state.String())
+	}
+
+	started := time.Now()
+	stateCtx, stopWatch := r.watch(ctx, state.String())
+	next, err := state.Run(stateCtx)
+	interrupted := stopWatch()
+	duration := time.Since(started)
+
+	for _, o := range r.observers {
+		o.OnExit(ctx, state.String(), duration, err)
+	}
+	// The state returned because the watchdog cancelled it, its own choice of the next state is discarded
+	if interrupted && ctx.Err() == nil {
+		if err != nil {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "Interrupted state returned an error", slog.String("error", err.Error()))
+		}
+		next, err = r.recoverState(state)
+		if err != nil {
+			return nil, fmt.Errorf("state %s recovery: %w", state.String(), err)
+		}
+		if err := r.transition(ctx, state, next, ReasonWatchdog, duration); err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+	if err != nil {
+		r.notify(ctx, state.String(), Terminal, ReasonFailed, duration)
+		return nil, fmt.Errorf("state %s run: %w", state.String(), err)
+	}
+	if err := r.transition(ctx, state, next, ReasonCompleted, duration); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// transition validates the edge between the states and notifies observers about it
+func (r *LoopRunner) transition(ctx context.Context, from, to states.AutomataState, reason Reason, duration time.Duration) error {
+	if err := checkTransition(from, to); err != nil {
+		r.logger.LogAttrs(ctx, slog.LevelError, "Invalid transition", slog.String("error", err.Error()))
+		return err
+	}
+	r.notify(ctx, nameOf(from), nameOf(to), reason, duration)
+	return nil
+}
+
+func (r *LoopRunner) notify(ctx context.Context, from, to string, reason Reason, duration time.Duration) {
+	for _, o := range r.observers {
+		o.OnTransition(ctx, from, to, reason, duration)
+	}
+}
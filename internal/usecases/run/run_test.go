@@ -0,0 +1,286 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// scripted is a state with the given name whose behaviour is defined by the test
+type scripted struct {
+	name string
+	run  func(ctx context.Context) (states.AutomataState, error)
+}
+
+func (s *scripted) Run(ctx context.Context) (states.AutomataState, error) {
+	if s.run == nil {
+		return nil, nil //nolint:nilnil // nil state ends the machine
+	}
+	return s.run(ctx)
+}
+
+func (s *scripted) String() string {
+	return s.name
+}
+
+// next returns a state that moves to the given state right away
+func next(name string, to states.AutomataState) *scripted {
+	return &scripted{name: name, run: func(context.Context) (states.AutomataState, error) {
+		return to, nil
+	}}
+}
+
+var _ factory.StateFactory = &fakeFactory{}
+
+// fakeFactory hands out the queued states of every name in order
+type fakeFactory struct {
+	mu     sync.Mutex
+	queued map[string][]states.AutomataState
+}
+
+func newFakeFactory(queued ...states.AutomataState) *fakeFactory {
+	f := &fakeFactory{queued: make(map[string][]states.AutomataState)}
+	for _, state := range queued {
+		f.queued[state.String()] = append(f.queued[state.String()], state)
+	}
+	return f
+}
+
+func (f *fakeFactory) get(name string) (states.AutomataState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	queue := f.queued[name]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("no %s state queued", name)
+	}
+	f.queued[name] = queue[1:]
+	return queue[0], nil
+}
+
+func (f *fakeFactory) GetInitState() (states.AutomataState, error) {
+	return f.get(states.Init)
+}
+
+func (f *fakeFactory) GetFailoverState() (states.AutomataState, error) {
+	return f.get(states.Failover)
+}
+
+func (f *fakeFactory) GetAttempterState() (states.AutomataState, error) {
+	return f.get(states.Attempter)
+}
+
+func (f *fakeFactory) GetLeaderState() (states.AutomataState, error) {
+	return f.get(states.Leader)
+}
+
+func (f *fakeFactory) GetStoppingState() (states.AutomataState, error) {
+	return f.get(states.Stopping)
+}
+
+func (f *fakeFactory) GetDrainingState(states.Drainer) (states.AutomataState, error) {
+	return f.get(states.Draining)
+}
+
+func (f *fakeFactory) GetMaintenanceState() (states.AutomataState, error) {
+	return f.get(states.Maintenance)
+}
+
+func (f *fakeFactory) Terminate(termination.Reason, error) (states.AutomataState, error) {
+	return f.get(states.Stopping)
+}
+
+func (f *fakeFactory) GetErrorState(error) (states.AutomataState, error) {
+	return f.get(states.Failover)
+}
+
+func (f *fakeFactory) SetElectionNode(string) error {
+	return nil
+}
+
+func (f *fakeFactory) GetElectionNode() (string, error) {
+	return "", nil
+}
+
+func (f *fakeFactory) ClearElectionNode() {}
+
+// event is a transition seen by the recorder
+type event struct {
+	from, to string
+	reason   Reason
+}
+
+// recorder is an observer collecting transitions
+type recorder struct {
+	NopObserver
+	mu     sync.Mutex
+	events []event
+}
+
+func (r *recorder) OnTransition(_ context.Context, from, to string, reason Reason, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event{from: from, to: to, reason: reason})
+}
+
+func (r *recorder) expect(t *testing.T, want ...event) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fmt.Sprint(r.events) != fmt.Sprint(want) {
+		t.Errorf("transitions:\n got %v\nwant %v", r.events, want)
+	}
+}
+
+func newTestRunner(f factory.StateFactory) (*LoopRunner, *recorder) {
+	r := NewLoopRunner(slog.New(slog.NewTextHandler(io.Discard, nil)), f)
+	rec := &recorder{}
+	r.Observe(rec)
+	return r, rec
+}
+
+func TestRunFollowsTransitions(t *testing.T) {
+	stopping := &scripted{name: states.Stopping}
+	first := next(states.Init, next(states.Attempter, stopping))
+	r, rec := newTestRunner(newFakeFactory())
+
+	if err := r.Run(context.Background(), first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Attempter, ReasonCompleted},
+		event{states.Attempter, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestRunRejectsInvalidTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    states.AutomataState
+		from, to string
+	}{
+		{
+			name:  "returned by a state",
+			first: next(states.Init, &scripted{name: states.Leader}),
+			from:  states.Init,
+			to:    states.Leader,
+		},
+		{
+			name:  "first state",
+			first: &scripted{name: states.Leader},
+			from:  Terminal,
+			to:    states.Leader,
+		},
+		{
+			name:  "ending the machine outside of Stopping",
+			first: &scripted{name: states.Init},
+			from:  states.Init,
+			to:    Terminal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRunner(newFakeFactory())
+			err := r.Run(context.Background(), tt.first)
+			var transitionErr *TransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("Run error = %v, want a TransitionError", err)
+			}
+			if transitionErr.From != tt.from || transitionErr.To != tt.to {
+				t.Errorf("TransitionError = %s -> %s, want %s -> %s", transitionErr.From, transitionErr.To, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestRunStateError(t *testing.T) {
+	errBroken := errors.New("broken")
+	first := &scripted{name: states.Init, run: func(context.Context) (states.AutomataState, error) {
+		return nil, errBroken
+	}}
+	r, rec := newTestRunner(newFakeFactory())
+
+	if err := r.Run(context.Background(), first); !errors.Is(err, errBroken) {
+		t.Fatalf("Run error = %v, want %v", err, errBroken)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, Terminal, ReasonFailed},
+	)
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var drained bool
+	stopping := &scripted{name: states.Stopping}
+	draining := &scripted{name: states.Draining, run: func(ctx context.Context) (states.AutomataState, error) {
+		drained = ctx.Err() != nil
+		return stopping, nil
+	}}
+	leader := &scripted{name: states.Leader, run: func(context.Context) (states.AutomataState, error) {
+		cancel()
+		return draining, nil
+	}}
+	// The attempter is replaced by Stopping, it is never run after the cancellation
+	attempter := &scripted{name: states.Attempter, run: func(context.Context) (states.AutomataState, error) {
+		t.Error("attempter ran after the context was cancelled")
+		return nil, nil //nolint:nilnil // never reached
+	}}
+	first := next(states.Init, next(states.Attempter, leader))
+	r, rec := newTestRunner(newFakeFactory(&scripted{name: states.Stopping}))
+
+	if err := r.Run(ctx, first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !drained {
+		t.Error("Draining was not run to the end after the cancellation")
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Attempter, ReasonCompleted},
+		event{states.Attempter, states.Leader, ReasonCompleted},
+		event{states.Leader, states.Draining, ReasonCompleted},
+		event{states.Draining, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+
+	// A state that is not on the shutdown path is replaced with Stopping
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	r, rec = newTestRunner(newFakeFactory(&scripted{name: states.Stopping}))
+	if err := r.Run(cancelled, next(states.Init, attempter)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Stopping, ReasonCancelled},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestKnown(t *testing.T) {
+	for _, state := range []string{states.Init, states.Attempter, states.Leader, states.Failover,
+		states.Draining, states.Stopping, states.Maintenance} {
+		if !known(state) {
+			t.Errorf("state %s is not in the transition table", state)
+		}
+	}
+	for _, state := range []string{Terminal, states.Empty, "Unknown"} {
+		if known(state) {
+			t.Errorf("state %s is known", state)
+		}
+	}
+}
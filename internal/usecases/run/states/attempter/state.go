@@ -2,89 +2,322 @@ package attempter
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/follower"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/partition"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
 	"github.com/go-zookeeper/zk"
 )
 
-const electionPath = "/election"
+const (
+	electionPath = "/election"
+	nodePrefix   = "guid-n_"
+	// protectedPrefix marks znodes named with a candidate GUID, as in zk.Conn.CreateProtectedEphemeralSequential
+	protectedPrefix = "_c_"
+	// createAttempts bounds the retries of the znode creation after a connection loss
+	createAttempts = 3
+)
 
-func New(logger *slog.Logger, config config.Config, conn *zk.Conn, factory factory.StateFactory) *State {
+func New(
+	logger *slog.Logger,
+	config config.Config,
+	conn *zk.Conn,
+	guid string,
+	worker *partition.Worker,
+	followers *follower.Runner,
+	maintenance *maintenance.Switch,
+	factory factory.StateFactory,
+) *State {
 	logger = logger.With("state", "attempterState")
 	return &State{
-		logger:  logger,
-		conn:    conn,
-		config:  config,
-		factory: factory,
+		logger:      logger,
+		conn:        conn,
+		guid:        guid,
+		config:      config,
+		worker:      worker,
+		followers:   followers,
+		maintenance: maintenance,
+		factory:     factory,
 	}
 }
 
 type State struct {
-	logger  *slog.Logger
-	conn    *zk.Conn
-	config  config.Config
-	factory factory.StateFactory
+	logger      *slog.Logger
+	conn        *zk.Conn
+	guid        string
+	config      config.Config
+	worker      *partition.Worker
+	followers   *follower.Runner
+	maintenance *maintenance.Switch
+	factory     factory.StateFactory
 }
 
 // String returns the name of the state
 func (s *State) String() string {
-	return "Attempter"
+	return states.Attempter
 }
 
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Attempting to become leader")
 
-	znode, err := s.conn.CreateProtectedEphemeralSequential(electionPath+"/guid-n_", nil, zk.WorldACL(zk.PermAll))
+	if s.maintenance.Enabled() {
+		return s.factory.GetMaintenanceState()
+	}
+
+	znode, err := s.ensureNode(ctx)
 	if err != nil {
 		s.logger.LogAttrs(ctx, slog.LevelError, "Error creating znode", slog.String("error", err.Error()))
+		return s.factory.GetErrorState(err)
+	}
+	// Recorded right away, so that Stopping deletes the znode of a follower too
+	if err := s.factory.SetElectionNode(znode); err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error on setting election node", slog.String("error", err.Error()))
 		return s.factory.GetFailoverState()
 	}
-	s.logger.LogAttrs(ctx, slog.LevelInfo, "Created znode", slog.String("znode", znode))
+
+	// Follower tasks keep the node warm until it becomes the leader or stops
+	if s.followers != nil {
+		stopFollowers := s.followers.Start(ctx)
+		defer stopFollowers()
+	}
+
+	// Followers process their share of partitions while waiting for leadership
+	if s.worker != nil {
+		workerCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.worker.Run(workerCtx, path.Base(znode))
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
 
 	ticker := time.NewTicker(s.config.LeaderTimeout)
 	defer ticker.Stop()
 
+	// The queue is checked at once and again as soon as a watch fires, the ticker only paces retries after errors
+	for {
+		maintenanceCh := s.maintenance.Changed()
+		if s.maintenance.Enabled() {
+			return s.withdraw(ctx, znode)
+		}
+		w, next, done, err := s.check(ctx, znode)
+		if done {
+			return next, err
+		}
+		if next, done, err := s.wait(ctx, znode, ticker, maintenanceCh, w); done {
+			return next, err
+		}
+	}
+}
+
+// watches are set on the predecessor and on the own znode, either change may make this node the leader or drop it
+type watches struct {
+	previous <-chan zk.Event
+	own      <-chan zk.Event
+}
+
+// check looks at the election queue once. It either decides the next state, then done is set,
+// or returns the watches to wait on, nil after an error that is retried on the next tick
+func (s *State) check(ctx context.Context, znode string) (*watches, states.AutomataState, bool, error) {
+	states.ReportProgress(ctx)
+
+	children, _, err := s.conn.Children(electionPath)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error getting children", slog.String("error", err.Error()))
+		return nil, nil, false, nil
+	}
+	queue := sortQueue(children)
+	index := indexOf(queue, path.Base(znode))
+	if index < 0 {
+		next, err := s.rejoin(ctx, znode, "Own znode disappeared, rejoining the election")
+		return nil, next, true, err
+	}
+
+	if index == 0 {
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "I am the leader")
+		next, err := s.factory.GetLeaderState()
+		return nil, next, true, err
+	}
+
+	previousZnode := queue[index-1]
+	_, _, previousCh, err := s.conn.ExistsW(electionPath + "/" + previousZnode)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error setting watch", slog.String("error", err.Error()))
+		return nil, nil, false, nil
+	}
+	exists, _, ownCh, err := s.conn.ExistsW(znode)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error setting watch", slog.String("error", err.Error()))
+		return nil, nil, false, nil
+	}
+	if !exists {
+		next, err := s.rejoin(ctx, znode, "Own znode disappeared, rejoining the election")
+		return nil, next, true, err
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Watching znode", slog.String("znode", previousZnode))
+	return &watches{previous: previousCh, own: ownCh}, nil, false, nil
+}
+
+// wait blocks until the queue has to be checked again. Without watches that happens on the next tick,
+// with them when a watch or the maintenance switch fires. The next state is returned when done is set
+func (s *State) wait(
+	ctx context.Context,
+	znode string,
+	ticker *time.Ticker,
+	maintenanceCh <-chan struct{},
+	w *watches,
+) (states.AutomataState, bool, error) {
+	var previousCh, ownCh <-chan zk.Event
+	if w != nil {
+		previousCh, ownCh = w.previous, w.own
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.LogAttrs(ctx, slog.LevelInfo, "Context done in attempter state")
-			return s.factory.GetStoppingState()
+			next, err := s.factory.GetStoppingState()
+			return next, true, err
+		case <-maintenanceCh:
+			return nil, false, nil
+		case <-previousCh:
+			return nil, false, nil
+		case event := <-ownCh:
+			if event.Type == zk.EventNodeDeleted {
+				next, err := s.rejoin(ctx, znode, "Own znode deleted, rejoining the election")
+				return next, true, err
+			}
+			return nil, false, nil
 		case <-ticker.C:
-
-			children, _, err := s.conn.Children(electionPath)
-			if err != nil {
-				s.logger.LogAttrs(ctx, slog.LevelError, "Error getting children", slog.String("error", err.Error()))
-				continue
+			if w == nil {
+				return nil, false, nil
 			}
-			sort.SliceStable(children, func(i, j int) bool {
-				znodeSeqI := strings.Split(children[i], "guid-n_")[1]
-				znodeSeqJ := strings.Split(children[j], "guid-n_")[1]
-				return znodeSeqI < znodeSeqJ
-			})
-
-			if znode == electionPath+"/"+children[0] {
-				s.logger.LogAttrs(ctx, slog.LevelInfo, "I am the leader")
-				return s.factory.GetLeaderState()
+			// Waiting on the watches is progress as long as the session that holds them is alive
+			if s.conn.State() == zk.StateHasSession {
+				states.ReportProgress(ctx)
 			}
+		}
+	}
+}
 
-			// Find the znode to watch
-			index := sort.SearchStrings(children, znode[len(electionPath)+1:])
-			if index > 0 {
-				previousZnode := children[index-1]
-				_, _, ch, err := s.conn.ExistsW(electionPath + "/" + previousZnode)
-				if err != nil {
-					s.logger.LogAttrs(ctx, slog.LevelError, "Error setting watch", slog.String("error", err.Error()))
-					continue
-				}
-				s.logger.LogAttrs(ctx, slog.LevelInfo, "Watching znode", slog.String("znode", previousZnode))
-				<-ch
-			}
+// rejoin forgets the lost candidate znode and starts the election over
+func (s *State) rejoin(ctx context.Context, znode, msg string) (states.AutomataState, error) {
+	s.logger.LogAttrs(ctx, slog.LevelWarn, msg, slog.String("znode", znode))
+	s.factory.ClearElectionNode()
+	return s.factory.GetAttempterState()
+}
+
+// withdraw deletes the candidate znode and moves the node into maintenance
+func (s *State) withdraw(ctx context.Context, znode string) (states.AutomataState, error) {
+	err := s.conn.Delete(znode, -1)
+	if err != nil && !errors.Is(err, zk.ErrNoNode) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error deleting znode", slog.String("error", err.Error()))
+		return s.factory.GetErrorState(err)
+	}
+	s.factory.ClearElectionNode()
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Candidacy withdrawn for maintenance", slog.String("znode", znode))
+	return s.factory.GetMaintenanceState()
+}
+
+// ensureNode returns the candidate znode of this node. A znode with the candidate GUID that belongs to
+// the current session is reused, one left by an earlier session is deleted, so the node never
+// stands in the queue twice
+func (s *State) ensureNode(ctx context.Context) (string, error) {
+	prefix := protectedPrefix + s.guid + "-"
+	for attempt := 1; ; attempt++ {
+		znode, found, err := s.findOwnNode(ctx, prefix)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Reusing own znode", slog.String("znode", znode))
+			return znode, nil
+		}
+
+		znode, err = s.conn.Create(electionPath+"/"+prefix+nodePrefix, nil, zk.FlagEphemeral|zk.FlagSequence, zk.WorldACL(zk.PermAll))
+		if err == nil {
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Created znode", slog.String("znode", znode))
+			return znode, nil
+		}
+		// The znode may have been created before the connection was lost, the next lookup finds it by the GUID
+		if !errors.Is(err, zk.ErrConnectionClosed) || attempt >= createAttempts {
+			return "", err
+		}
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Connection lost while creating znode, looking it up", slog.Int("attempt", attempt))
+	}
+}
+
+func (s *State) findOwnNode(ctx context.Context, prefix string) (string, bool, error) {
+	children, _, err := s.conn.Children(electionPath)
+	if err != nil {
+		return "", false, err
+	}
+	for _, child := range children {
+		if !strings.HasPrefix(child, prefix) {
+			continue
+		}
+		znode := electionPath + "/" + child
+		exists, stat, err := s.conn.Exists(znode)
+		if err != nil {
+			return "", false, err
+		}
+		if !exists {
+			continue
+		}
+		if stat.EphemeralOwner == s.conn.SessionID() {
+			return znode, true, nil
+		}
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Deleting own znode of an earlier session", slog.String("znode", znode))
+		if err := s.conn.Delete(znode, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return "", false, err
+		}
+	}
+	return "", false, nil
+}
+
+// sortQueue orders the election znodes by their sequence numbers, the GUID prefix does not affect the order
+func sortQueue(children []string) []string {
+	queue := make([]string, 0, len(children))
+	for _, child := range children {
+		if _, ok := sequenceOf(child); ok {
+			queue = append(queue, child)
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		seqI, _ := sequenceOf(queue[i])
+		seqJ, _ := sequenceOf(queue[j])
+		return seqI < seqJ
+	})
+	return queue
+}
+
+func sequenceOf(name string) (int64, bool) {
+	_, suffix, found := strings.Cut(name, nodePrefix)
+	if !found {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(suffix, 10, 64)
+	return seq, err == nil
+}
+
+func indexOf(queue []string, name string) int {
+	for i, child := range queue {
+		if child == name {
+			return i
 		}
 	}
+	return -1
 }
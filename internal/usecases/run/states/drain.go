@@ -0,0 +1,12 @@
+package states
+
+import (
+	"context"
+)
+
+// Drainer is the leader work handed over to the Draining state on shutdown
+type Drainer interface {
+	// Drain stops starting new work and awaits the in-flight one. When the context is done first,
+	// the in-flight work is cancelled and awaited. The returned error is the outcome of the work
+	Drain(ctx context.Context) error
+}
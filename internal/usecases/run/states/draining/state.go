@@ -0,0 +1,71 @@
+package draining
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/go-zookeeper/zk"
+)
+
+// New creates the Draining state of the leader owning the election znode node.
+// The work is given at most timeout to finish
+func New(logger *slog.Logger, conn *zk.Conn, node string, timeout time.Duration, work states.Drainer, factory factory.StateFactory) *State {
+	logger = logger.With("state", "DrainingState")
+	return &State{
+		logger:  logger,
+		conn:    conn,
+		node:    node,
+		timeout: timeout,
+		work:    work,
+		factory: factory,
+	}
+}
+
+// State represents the Draining state of the state machine. The leader finishes its in-flight work
+// and gives up the leadership before the node stops
+type State struct {
+	logger  *slog.Logger
+	conn    *zk.Conn
+	node    string
+	timeout time.Duration
+	work    states.Drainer
+	factory factory.StateFactory
+}
+
+// String returns the name of the state
+func (s *State) String() string {
+	return states.Draining
+}
+
+// Run drains the work and deletes the election znode afterwards, so the checkpoints the next leader
+// resumes from are complete. It runs on shutdown, so the deadline does not depend on the cancelled context
+func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Draining leader work", slog.Duration("timeout", s.timeout))
+
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := s.work.Drain(drainCtx)
+	if errors.Is(drainCtx.Err(), context.DeadlineExceeded) {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Drain deadline exceeded, in-flight work was cancelled")
+	}
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Leader work failed while draining", slog.String("error", err.Error()))
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader work drained, checkpoints flushed", slog.Duration("duration", time.Since(started)))
+
+	// The session is closed by Stopping anyway, a failed delete only delays the handover
+	err = s.conn.Delete(s.node, -1)
+	if err != nil && !errors.Is(err, zk.ErrNoNode) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error deleting election znode", slog.String("error", err.Error()))
+	} else {
+		s.factory.ClearElectionNode()
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Leadership released", slog.String("znode", s.node))
+	}
+	return s.factory.GetStoppingState()
+}
@@ -20,7 +20,7 @@ type State struct {
 }
 
 func (s *State) String() string {
-	return "EmptyState"
+	return states.Empty
 }
 
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
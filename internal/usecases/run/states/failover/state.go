@@ -5,19 +5,22 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backoff"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
-
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
-	"github.com/go-zookeeper/zk"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/zkconn"
 )
 
 // New creates a new instance of the Failover state
-func New(logger *slog.Logger, config config.Config, factory factory.StateFactory) *State {
+func New(logger *slog.Logger, config config.Config, conns *zkconn.Manager, policy backoff.Policy, factory factory.StateFactory) *State {
 	logger = logger.With("state", "FailoverState")
 	return &State{
 		logger:  logger,
 		config:  config,
+		conns:   conns,
+		policy:  policy,
 		factory: factory,
 	}
 }
@@ -26,40 +29,57 @@ func New(logger *slog.Logger, config config.Config, factory factory.StateFactory
 type State struct {
 	logger  *slog.Logger
 	config  config.Config
+	conns   *zkconn.Manager
+	policy  backoff.Policy
 	factory factory.StateFactory
 }
 
 func (s *State) String() string {
-	return "Failover"
+	return states.Failover
 }
 
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Entering failover state")
 
-	retryIntervals := []time.Duration{0, 1 * time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second}
-
-	for i, interval := range retryIntervals {
+	var lastErr error
+	for attempt := 0; !s.policy.Exhausted(attempt); attempt++ {
+		delay := s.policy.Delay(attempt)
 		select {
 		case <-ctx.Done():
 			return s.factory.GetStoppingState()
-		case <-time.After(interval):
-			s.logger.LogAttrs(ctx, slog.LevelInfo, "Attempting to recover connection to Zookeeper", slog.Int("attempt", i+1))
+		case <-time.After(delay):
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Attempting to recover connection to Zookeeper",
+				slog.Int("attempt", attempt+1), slog.Duration("delay", delay))
+			states.ReportProgress(ctx)
 
-			conn, _, err := zk.Connect(s.config.ZookeeperServers, 10*time.Second)
-			if conn != nil || err != nil {
+			err := s.reconnect(ctx)
+			if err == nil {
 				s.logger.LogAttrs(ctx, slog.LevelInfo, "Successfully reconnected to Zookeeper")
 				// Assuming that the Init state is the entry point after a successful reconnection
 				initState, err := s.factory.GetInitState()
 				if err != nil {
 					s.logger.LogAttrs(ctx, slog.LevelError, "Failed to get init state after recovery", slog.String("error", err.Error()))
-					return s.factory.GetStoppingState()
+					return s.factory.Terminate(termination.Unexpected, err)
 				}
 				return initState, nil
 			}
 			s.logger.LogAttrs(ctx, slog.LevelError, "Failed to reconnect to Zookeeper", slog.String("error", err.Error()))
+			lastErr = err
+			// Retrying cannot fix permission or argument problems
+			if zkconn.Classify(err) == zkconn.ActionStop {
+				return s.factory.GetErrorState(err)
+			}
 		}
 	}
 
 	s.logger.LogAttrs(ctx, slog.LevelError, "Max recovery attempts reached, transitioning to stopping state")
-	return s.factory.GetStoppingState()
+	return s.factory.Terminate(termination.FailoverExhausted, lastErr)
+}
+
+// reconnect replaces the connection and probes it, the connection counts as recovered only when the server answers
+func (s *State) reconnect(ctx context.Context) error {
+	if _, err := s.conns.Reconnect(ctx); err != nil {
+		return err
+	}
+	return s.conns.Probe(ctx)
 }
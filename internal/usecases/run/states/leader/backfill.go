@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/output"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/scheduler"
+)
+
+// BackfillPolicy defines what a new leader does with intervals that got no output while there was no leader
+type BackfillPolicy string
+
+const (
+	// BackfillNone leaves the intervals without output
+	BackfillNone BackfillPolicy = "none"
+	// BackfillFlag writes a gap record for every missed interval
+	BackfillFlag BackfillPolicy = "flag"
+	// BackfillFill writes a regular record, marked as backfilled, for every missed interval
+	BackfillFill BackfillPolicy = "fill"
+)
+
+// ParseBackfillPolicy validates the policy name
+func ParseBackfillPolicy(s string) (BackfillPolicy, error) {
+	switch p := BackfillPolicy(s); p {
+	case BackfillNone, BackfillFlag, BackfillFill:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown backfill policy %q, expected one of: none, flag, fill", s)
+	}
+}
+
+// fillGaps produces or flags records for activations that had no output since the last one
+func (o *outputJob) fillGaps(ctx context.Context) error {
+	if o.backfill == BackfillNone {
+		return nil
+	}
+	if o.claims != nil {
+		if err := o.claims.Load(); err != nil {
+			return err
+		}
+	}
+
+	last, err := o.lastOutput(ctx)
+	if err != nil {
+		return err
+	}
+	missed, truncated := schedule.Missed(o.schedule, schedule.MisfireAll, last, time.Now())
+	if len(missed) == 0 {
+		return nil
+	}
+	if truncated {
+		o.logger.LogAttrs(ctx, slog.LevelWarn, "Too many missed intervals, backfilling only the latest", slog.Int("count", len(missed)))
+	}
+	o.logger.LogAttrs(ctx, slog.LevelInfo, "Backfilling missed intervals", slog.Int("count", len(missed)),
+		slog.Time("from", missed[0]), slog.Time("to", missed[len(missed)-1]), slog.String("policy", string(o.backfill)))
+
+	if len(missed) > o.config.StorageCapacity {
+		o.logger.LogAttrs(ctx, slog.LevelWarn, "More missed intervals than the storage capacity, the oldest records are pruned",
+			slog.Int("count", len(missed)), slog.Int("capacity", o.config.StorageCapacity))
+	}
+
+	kind := output.KindBackfill
+	if o.backfill == BackfillFlag {
+		kind = output.KindGap
+	}
+	// Old files are pruned once for the whole backfill instead of after every record
+	defer o.prune(ctx)
+	for _, at := range missed {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := o.produceClaimed(ctx, kind, at); err != nil {
+			return fmt.Errorf("backfill interval %s: %w", at.Format(time.RFC3339), err)
+		}
+	}
+	return nil
+}
+
+// produceClaimed claims the interval as a tick before producing it when ticks are claimed,
+// so backfilled intervals share the numbering of scheduled ticks and are never produced twice
+func (o *outputJob) produceClaimed(ctx context.Context, kind output.Kind, at time.Time) error {
+	if o.claims == nil {
+		return o.produce(ctx, kind, at, 0)
+	}
+
+	// An unfinished tick of the previous leader blocks new claims until it is replayed
+	if pending, ok := o.claims.Pending(); ok {
+		if err := o.produce(ctx, output.KindTick, pending.Scheduled, pending.Seq); err != nil {
+			return err
+		}
+		if err := o.claims.Complete(pending); err != nil {
+			return scheduler.Abort(err)
+		}
+	}
+
+	tick, ok, err := o.claims.Claim(at)
+	if err != nil {
+		return scheduler.Abort(err)
+	}
+	if !ok {
+		o.logger.LogAttrs(ctx, slog.LevelInfo, "Interval is already claimed, skipping", slog.Time("scheduled", at))
+		return nil
+	}
+	if err := o.produce(ctx, kind, at, tick.Seq); err != nil {
+		return err
+	}
+	if err := o.claims.Complete(tick); err != nil {
+		return scheduler.Abort(err)
+	}
+	return nil
+}
+
+// lastOutput returns the activation of the newest record. The checkpoint is preferred,
+// the modification time of the newest file in the sink is used when there is no checkpoint yet
+func (o *outputJob) lastOutput(ctx context.Context) (time.Time, error) {
+	if !o.progress.Scheduled.IsZero() {
+		return o.progress.Scheduled, nil
+	}
+	if !o.progress.Time.IsZero() {
+		return o.progress.Time, nil
+	}
+
+	objects, err := o.sink.List(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("list files for backfill: %w", err)
+	}
+	var newest time.Time
+	for _, object := range objects {
+		if object.ModTime.After(newest) {
+			newest = object.ModTime
+		}
+	}
+	return newest, nil
+}
@@ -0,0 +1,42 @@
+package leader
+
+import (
+	"context"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/scheduler"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+var _ states.Drainer = &work{}
+
+// work is the running scheduler and partition work of a leader. It outlives the state on shutdown,
+// when it is handed over to the Draining state
+type work struct {
+	sched          *scheduler.Scheduler
+	done           <-chan error
+	cancel         context.CancelFunc
+	stopPartitions func()
+}
+
+// Drain stops scheduling new activations and awaits the in-flight ones.
+// Every activation saves its checkpoint before it returns, so the checkpoints are flushed afterwards
+func (w *work) Drain(ctx context.Context) error {
+	w.sched.Stop()
+	var err error
+	select {
+	case err = <-w.done:
+	case <-ctx.Done():
+		w.cancel()
+		err = <-w.done
+	}
+	w.cancel()
+	w.stopPartitions()
+	return err
+}
+
+// abandon cancels the work and awaits it
+func (w *work) abandon() {
+	w.cancel()
+	<-w.done
+	w.stopPartitions()
+}
@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/output"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/scheduler"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// outputJob writes the leader files within a single term
+type outputJob struct {
+	*State
+	term     int64
+	hostname string
+	progress progress
+	// claims numbers backfilled intervals, nil when ticks are not claimed
+	claims *ticks.Claimer
+}
+
+// job wraps the output work into a scheduler job. Activations never overlap, so progress needs no locking.
+// Missed activations are left to backfill when it is enabled
+func (o *outputJob) job() scheduler.Job {
+	misfire := o.misfire
+	if o.backfill != BackfillNone {
+		misfire = schedule.MisfireSkip
+	}
+	return scheduler.Job{
+		Name:        outputTask,
+		Schedule:    o.schedule,
+		Misfire:     misfire,
+		Concurrency: 1,
+		Delivery:    o.delivery,
+		Backfilled:  o.backfill != BackfillNone,
+		Run:         o.run,
+	}
+}
+
+// run writes the file of a scheduled activation and prunes old files
+func (o *outputJob) run(ctx context.Context, act scheduler.Activation) error {
+	if err := o.produce(ctx, output.KindTick, act.Scheduled, act.Seq); err != nil {
+		return err
+	}
+	o.prune(ctx)
+	return nil
+}
+
+// produce writes a record of the given kind and checkpoints it.
+// The sequence number of a claimed tick is used when it is set, otherwise the local counter continues.
+// A checkpoint conflict aborts the scheduler, it means that this node is no longer the leader
+func (o *outputJob) produce(ctx context.Context, kind output.Kind, scheduled time.Time, seq uint64) error {
+	if seq == 0 {
+		seq = o.progress.Seq + 1
+	}
+	now := time.Now()
+	name, err := o.writeFile(ctx, output.Record{
+		Kind:      kind,
+		Term:      o.term,
+		NodeID:    o.config.NodeID,
+		Hostname:  o.hostname,
+		Seq:       seq,
+		Time:      now,
+		Scheduled: scheduled,
+		Uptime:    output.Uptime(),
+	})
+	if err != nil {
+		return err
+	}
+	o.logger.LogAttrs(ctx, slog.LevelInfo, "Wrote to file", slog.String("file", name),
+		slog.String("kind", string(kind)), slog.String("sink", o.sink.String()))
+	states.ReportProgress(ctx)
+
+	o.progress.Term, o.progress.Seq, o.progress.Time, o.progress.Scheduled = o.term, seq, now, scheduled
+	err = o.progress.save(o.checkpoints)
+	if errors.Is(err, checkpoint.ErrConflict) {
+		return scheduler.Abort(err)
+	}
+	if err != nil {
+		o.logger.LogAttrs(ctx, slog.LevelError, "Error saving checkpoint", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// prune keeps the number of files in the sink within the storage capacity
+func (o *outputJob) prune(ctx context.Context) {
+	if err := o.manageFiles(ctx); err != nil {
+		o.logger.LogAttrs(ctx, slog.LevelError, "Error managing files", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,52 @@
+package leader
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
+)
+
+// outputTask is the checkpoint name of the periodic file writing
+const outputTask = "leader-output"
+
+// progress is the checkpointed state of the output task, a new leader continues from it
+type progress struct {
+	Term      int64     `json:"term"`
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Scheduled time.Time `json:"scheduled"`
+
+	version int32
+}
+
+// loadProgress reads the last checkpoint of the output task
+func loadProgress(store checkpoint.Store) (progress, error) {
+	cp, err := store.Load(outputTask)
+	if err != nil {
+		return progress{}, err
+	}
+	p := progress{version: cp.Version}
+	if cp.Version == checkpoint.NoVersion {
+		return p, nil
+	}
+	if err := json.Unmarshal(cp.Data, &p); err != nil {
+		return progress{}, fmt.Errorf("decode %s checkpoint: %w", outputTask, err)
+	}
+	return p, nil
+}
+
+// save stores the progress using compare-and-set on the version it was loaded at
+func (p *progress) save(store checkpoint.Store) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encode %s checkpoint: %w", outputTask, err)
+	}
+	version, err := store.Save(outputTask, data, p.version)
+	if err != nil {
+		return err
+	}
+	p.version = version
+	return nil
+}
@@ -2,96 +2,300 @@ package leader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"sort"
-	"time"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/checkpoint"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
-
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/output"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/partition"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/schedule"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/scheduler"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/ticks"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/go-zookeeper/zk"
 )
 
+const nodePrefix = "guid-n_"
+
 // New creates a new instance of the Leader state
-func New(logger *slog.Logger, config config.Config, factory factory.StateFactory) *State {
+func New(
+	logger *slog.Logger,
+	config config.Config,
+	conn *zk.Conn,
+	node string,
+	renderer *output.Renderer,
+	sink output.Sink,
+	checkpoints checkpoint.Store,
+	sched schedule.Schedule,
+	misfire schedule.MisfirePolicy,
+	backfill BackfillPolicy,
+	delivery ticks.Mode,
+	jobs []scheduler.Job,
+	policy scheduler.FailurePolicy,
+	coordinator *partition.Coordinator,
+	worker *partition.Worker,
+	maintenance *maintenance.Switch,
+	factory factory.StateFactory,
+) *State {
 	logger = logger.With("state", "LeaderState")
 	return &State{
-		logger:  logger,
-		config:  config,
-		factory: factory,
+		logger:      logger,
+		config:      config,
+		conn:        conn,
+		node:        node,
+		renderer:    renderer,
+		sink:        sink,
+		checkpoints: checkpoints,
+		schedule:    sched,
+		misfire:     misfire,
+		backfill:    backfill,
+		delivery:    delivery,
+		jobs:        jobs,
+		policy:      policy,
+		coordinator: coordinator,
+		worker:      worker,
+		maintenance: maintenance,
+		factory:     factory,
 	}
 }
 
 // State represents the Leader state of the state machine
 type State struct {
-	logger  *slog.Logger
-	config  config.Config
-	factory factory.StateFactory
+	logger      *slog.Logger
+	config      config.Config
+	conn        *zk.Conn
+	node        string
+	renderer    *output.Renderer
+	sink        output.Sink
+	checkpoints checkpoint.Store
+	schedule    schedule.Schedule
+	misfire     schedule.MisfirePolicy
+	backfill    BackfillPolicy
+	delivery    ticks.Mode
+	jobs        []scheduler.Job
+	policy      scheduler.FailurePolicy
+	coordinator *partition.Coordinator
+	worker      *partition.Worker
+	maintenance *maintenance.Switch
+	factory     factory.StateFactory
 }
 
 func (s *State) String() string {
-	return "Leader"
+	return states.Leader
 }
 
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Became leader, starting work")
 
-	ticker := time.NewTicker(s.config.LeaderTimeout)
-	defer ticker.Stop()
+	node := s.node
+	term, err := termFromNode(node)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error parsing leader term", slog.String("error", err.Error()))
+		return s.factory.GetFailoverState()
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Leader term acquired", slog.Int64("term", term))
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Error getting hostname", slog.String("error", err.Error()))
+	}
+
+	// Resume numbering from the checkpoint of the previous leader
+	last, err := loadProgress(s.checkpoints)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error loading checkpoint", slog.String("error", err.Error()))
+		return s.factory.GetErrorState(err)
+	}
+	if last.version != checkpoint.NoVersion {
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Resuming from checkpoint",
+			slog.Int64("term", last.Term), slog.Uint64("seq", last.Seq), slog.Time("time", last.Time))
+	}
+
+	out := &outputJob{
+		State:    s,
+		term:     term,
+		hostname: hostname,
+		progress: last,
+	}
+	if s.delivery != ticks.ModeOff {
+		out.claims = ticks.NewClaimer(s.checkpoints, outputTask, s.delivery)
+	}
+	err = out.fillGaps(ctx)
+	if scheduler.IsAbort(err) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Checkpoint was taken over by another leader", slog.String("error", err.Error()))
+		return s.factory.GetFailoverState()
+	}
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error backfilling missed intervals", slog.String("error", err.Error()))
+	}
+
+	jobs := append([]scheduler.Job{out.job()}, s.jobs...)
+	sched, err := scheduler.New(s.logger, s.checkpoints, s.policy, jobs)
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error creating scheduler", slog.String("error", err.Error()))
+		return s.factory.GetFailoverState()
+	}
+
+	// The work outlives the state context, so that it can be drained on shutdown
+	workCtx, stopWork := context.WithCancel(context.WithoutCancel(ctx))
+	w := &work{
+		sched:          sched,
+		cancel:         stopWork,
+		stopPartitions: s.startPartitions(workCtx, path.Base(node)),
+	}
+	// Maintenance drains the leader work like a shutdown, the leadership is released afterwards
+	maintenanceRequested := make(chan struct{})
+	go s.awaitMaintenance(workCtx, maintenanceRequested)
+
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Starting jobs", slog.Int("count", len(jobs)))
+	done := make(chan error, 1)
+	w.done = done
+	go func() {
+		done <- sched.Run(workCtx)
+	}()
+
+	select {
+	case err = <-done:
+		stopWork()
+		w.stopPartitions()
+	case <-ctx.Done():
+		// The watchdog cancels the state with its own cause, the runner then moves on without draining
+		if !errors.Is(context.Cause(ctx), context.Canceled) {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "Leader state interrupted, cancelling work")
+			w.abandon()
+			return s.factory.GetStoppingState()
+		}
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Context done in leader state, draining work")
+		return s.factory.GetDrainingState(w)
+	case <-maintenanceRequested:
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Maintenance requested, draining work before giving up leadership",
+			slog.Duration("timeout", s.config.DrainTimeout))
+		drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.DrainTimeout)
+		err := w.Drain(drainCtx)
+		if errors.Is(drainCtx.Err(), context.DeadlineExceeded) {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "Drain deadline exceeded, in-flight work was cancelled")
+		}
+		cancel()
+		if err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelError, "Leader work failed while draining", slog.String("error", err.Error()))
+		}
+		return s.release(ctx, node, s.factory.GetMaintenanceState)
+	}
+	if errors.Is(err, scheduler.ErrUnhealthy) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Leader work is failing, giving up leadership", slog.String("error", err.Error()))
+		return s.stepDown(ctx, node)
+	}
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Scheduler aborted", slog.String("error", err.Error()))
+		return s.factory.GetErrorState(err)
+	}
+
+	// The scheduler returns nil only when it is stopped or cancelled, which does not happen here
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "Leader work ended, giving up leadership")
+	return s.stepDown(ctx, node)
+}
+
+// stepDown deletes the election znode, so the next candidate becomes the leader,
+// and rejoins the election at the end of the queue
+func (s *State) stepDown(ctx context.Context, node string) (states.AutomataState, error) {
+	return s.release(ctx, node, s.factory.GetAttempterState)
+}
 
+// release deletes the election znode and moves to the next state
+func (s *State) release(ctx context.Context, node string, next func() (states.AutomataState, error)) (states.AutomataState, error) {
+	err := s.conn.Delete(node, -1)
+	if err != nil && !errors.Is(err, zk.ErrNoNode) {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Error deleting election znode", slog.String("error", err.Error()))
+		return s.factory.GetErrorState(err)
+	}
+	s.factory.ClearElectionNode()
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Leadership released", slog.String("znode", node))
+	return next()
+}
+
+// awaitMaintenance closes requested when the node is put into maintenance
+func (s *State) awaitMaintenance(ctx context.Context, requested chan<- struct{}) {
 	for {
+		changed := s.maintenance.Changed()
+		if s.maintenance.Enabled() {
+			close(requested)
+			return
+		}
 		select {
 		case <-ctx.Done():
-			s.logger.LogAttrs(ctx, slog.LevelInfo, "Context done in leader state")
-			return s.factory.GetStoppingState()
-		case <-ticker.C:
-			filePath := filepath.Join(s.config.FileDir, fmt.Sprintf("leader_%d.txt", time.Now().Unix()))
-			err := os.WriteFile(filePath, []byte("Leader active"), 0o644)
-			if err != nil {
-				s.logger.LogAttrs(ctx, slog.LevelError, "Error writing to file", slog.String("error", err.Error()))
-				continue
-			}
-			s.logger.LogAttrs(ctx, slog.LevelInfo, "Wrote to file", slog.String("file", filePath))
-
-			// Manage files in the directory
-			err = s.manageFiles(ctx)
-			if err != nil {
-				s.logger.LogAttrs(ctx, slog.LevelError, "Error managing files", slog.String("error", err.Error()))
-			}
+			return
+		case <-changed:
 		}
 	}
 }
 
-// manageFiles ensures that the number of files in the directory does not exceed the storage capacity
-func (s *State) manageFiles(ctx context.Context) error {
-	files, err := os.ReadDir(s.config.FileDir)
+// startPartitions runs the coordinator and the worker of the leader's own partitions in background.
+// The returned function stops and awaits them
+func (s *State) startPartitions(ctx context.Context, member string) func() {
+	if s.coordinator == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.coordinator.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		s.worker.Run(ctx, member)
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// writeFile renders the record and puts it into the sink
+func (s *State) writeFile(ctx context.Context, rec output.Record) (string, error) {
+	name, err := s.renderer.Name(rec)
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	if len(files) > s.config.StorageCapacity {
-		// Sort files by modification time
-		sort.Slice(files, func(i, j int) bool {
-			fileInfoI, _ := files[i].Info()
-			fileInfoJ, _ := files[j].Info()
-
-			return fileInfoI.ModTime().Before(fileInfoJ.ModTime())
-		})
-
-		// Remove oldest files if exceeding storage capacity
-		excess := len(files) - s.config.StorageCapacity
-		for i := 0; i < excess; i++ {
-			filePath := filepath.Join(s.config.FileDir, files[i].Name())
-			err := os.Remove(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to remove file %s: %w", filePath, err)
-			}
-			s.logger.LogAttrs(ctx, slog.LevelInfo, "Removed file", slog.String("file", filePath))
-		}
+		return "", err
+	}
+	content, err := s.renderer.Content(rec)
+	if err != nil {
+		return "", err
+	}
+	if err := s.sink.Put(ctx, name, content); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// termFromNode extracts the sequence number of the election znode, which grows with every new
+// candidate and therefore serves as a fencing term for the leader
+func termFromNode(node string) (int64, error) {
+	_, seq, found := strings.Cut(path.Base(node), nodePrefix)
+	if !found {
+		return 0, fmt.Errorf("znode %s has no sequence suffix", node)
+	}
+	term, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse sequence of znode %s: %w", node, err)
 	}
+	return term, nil
+}
 
-	return nil
+// manageFiles ensures that the number of files in the sink does not exceed the storage capacity
+func (s *State) manageFiles(ctx context.Context) error {
+	removed, err := output.Prune(ctx, s.sink, s.config.StorageCapacity)
+	for _, name := range removed {
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Removed file", slog.String("file", name))
+	}
+	return err
 }
@@ -0,0 +1,50 @@
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/maintenance"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// New creates a new instance of the Maintenance state
+func New(logger *slog.Logger, maintenance *maintenance.Switch, factory factory.StateFactory) *State {
+	logger = logger.With("state", "MaintenanceState")
+	return &State{
+		logger:      logger,
+		maintenance: maintenance,
+		factory:     factory,
+	}
+}
+
+// State represents the Maintenance state of the state machine. The node keeps its connection
+// but has no znode in the election until the maintenance is released
+type State struct {
+	logger      *slog.Logger
+	maintenance *maintenance.Switch
+	factory     factory.StateFactory
+}
+
+// String returns the name of the state
+func (s *State) String() string {
+	return states.Maintenance
+}
+
+func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "Node is in maintenance, staying out of the election")
+	for {
+		changed := s.maintenance.Changed()
+		if !s.maintenance.Enabled() {
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Maintenance released, rejoining the election")
+			return s.factory.GetAttempterState()
+		}
+		select {
+		case <-ctx.Done():
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "Context done in maintenance state")
+			return s.factory.GetStoppingState()
+		case <-changed:
+		}
+	}
+}
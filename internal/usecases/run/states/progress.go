@@ -0,0 +1,19 @@
+package states
+
+import (
+	"context"
+)
+
+type progressKey struct{}
+
+// WithProgress returns a context through which the running state reports its progress to the runner
+func WithProgress(ctx context.Context, report func()) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// ReportProgress tells the runner that the state is not stuck, it does nothing outside of the runner
+func ReportProgress(ctx context.Context) {
+	if report, ok := ctx.Value(progressKey{}).(func()); ok {
+		report()
+	}
+}
@@ -4,6 +4,18 @@ import (
 	"context"
 )
 
+// Names of the states, returned by their String methods and used in the transition table
+const (
+	Init        = "InitState"
+	Attempter   = "Attempter"
+	Leader      = "Leader"
+	Failover    = "Failover"
+	Draining    = "Draining"
+	Stopping    = "Stopping"
+	Maintenance = "Maintenance"
+	Empty       = "EmptyState"
+)
+
 type AutomataState interface {
 	Run(ctx context.Context) (AutomataState, error)
 	String() string
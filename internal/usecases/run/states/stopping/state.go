@@ -2,47 +2,83 @@ package stopping
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/config"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
 	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/zkconn"
 	"github.com/go-zookeeper/zk"
 )
 
-func New(logger *slog.Logger, conn *zk.Conn, config config.Config, factory factory.StateFactory) *State {
+// releaseTimeout bounds the wait for the deletion of the election znode, the session expiry removes it otherwise
+const releaseTimeout = 5 * time.Second
+
+// New creates the Stopping state, cause is nil for a graceful shutdown
+func New(logger *slog.Logger, conns *zkconn.Manager, config config.Config, cause *termination.Error, factory factory.StateFactory) *State {
 	logger = logger.With("state", "StoppingState")
 	return &State{
 		logger:  logger,
-		conn:    conn,
+		conns:   conns,
 		config:  config,
+		cause:   cause,
 		factory: factory,
 	}
 }
 
-// State represents the Init state of the state machine
+// State represents the Stopping state of the state machine
 type State struct {
 	logger  *slog.Logger
-	conn    *zk.Conn
+	conns   *zkconn.Manager
 	config  config.Config
+	cause   *termination.Error
 	factory factory.StateFactory
 }
 
 // String returns the name of the state
 func (s *State) String() string {
-	return "Stopping"
+	return states.Stopping
 }
 
-// Run executes the logic of the Init state
+// Run releases resources. It finishes the machine without an error on a graceful shutdown
+// and returns the termination cause otherwise
 func (s *State) Run(ctx context.Context) (states.AutomataState, error) {
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Entering stopping state")
 
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Releasing resources")
-	if s.conn != nil {
-		s.conn.Close()
+	if node, err := s.factory.GetElectionNode(); err == nil {
+		s.release(ctx, node)
 	}
+	s.conns.Close()
 
+	if s.cause != nil {
+		s.logger.LogAttrs(ctx, slog.LevelError, "Application stopped", slog.String("reason", string(s.cause.Reason)),
+			slog.String("error", s.cause.Error()))
+		return nil, s.cause
+	}
 	s.logger.LogAttrs(ctx, slog.LevelInfo, "Application stopped gracefully")
-	return nil, fmt.Errorf("stopping state: application stopped gracefully")
+	return nil, nil //nolint:nilnil // nil state ends the machine
+}
+
+// release deletes the election znode before the session is closed, so the next candidate takes over
+// at once instead of waiting for the session to expire
+func (s *State) release(ctx context.Context, node string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	err := s.conns.Delete(ctx, node)
+	switch {
+	case errors.Is(err, zk.ErrNoNode):
+		s.factory.ClearElectionNode()
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Election znode already released", slog.String("znode", node))
+	case err != nil:
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "Error deleting election znode, it is removed on session expiry",
+			slog.String("znode", node), slog.String("error", err.Error()))
+	default:
+		s.factory.ClearElectionNode()
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "Election znode deleted", slog.String("znode", node))
+	}
 }
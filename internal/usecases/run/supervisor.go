@@ -0,0 +1,124 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/backoff"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/depgraph/factory"
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
+)
+
+// RestartObserver is notified about every restart of the state machine
+type RestartObserver interface {
+	OnRestart(ctx context.Context, restart int, err error)
+}
+
+// RestartLimit bounds the rate of restarts, MaxRestarts zero means unlimited
+type RestartLimit struct {
+	MaxRestarts int
+	Window      time.Duration
+}
+
+// Supervisor restarts the state machine from Init after it fails, with crash-loop backoff.
+// Graceful stops, configuration and auth errors are not restarted
+type Supervisor struct {
+	logger    *slog.Logger
+	runner    Runner
+	factory   factory.StateFactory
+	policy    backoff.Policy
+	limit     RestartLimit
+	reset     func()
+	observers []RestartObserver
+}
+
+// NewSupervisor creates a supervisor, reset is called before every restart to reopen released resources
+func NewSupervisor(
+	logger *slog.Logger,
+	runner Runner,
+	factory factory.StateFactory,
+	policy backoff.Policy,
+	limit RestartLimit,
+	reset func(),
+) *Supervisor {
+	return &Supervisor{
+		logger:  logger.With("subsystem", "Supervisor"),
+		runner:  runner,
+		factory: factory,
+		policy:  policy,
+		limit:   limit,
+		reset:   reset,
+	}
+}
+
+// Observe subscribes the observer to restarts, it must be called before Run
+func (s *Supervisor) Observe(observer RestartObserver) {
+	s.observers = append(s.observers, observer)
+}
+
+// Run runs the state machine until it stops for good and returns the error of the last run
+func (s *Supervisor) Run(ctx context.Context) error {
+	var restarts []time.Time
+	consecutive := 0
+	for {
+		state, err := s.factory.GetInitState()
+		if err != nil {
+			return fmt.Errorf("get init state: %w", err)
+		}
+		started := time.Now()
+		err = s.runner.Run(ctx, state)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		switch reason := termination.ReasonOf(err); reason {
+		case termination.Configuration, termination.Auth:
+			s.logger.LogAttrs(ctx, slog.LevelError, "State machine failed permanently, not restarting",
+				slog.String("reason", string(reason)), slog.String("error", err.Error()))
+			return err
+		}
+
+		// A run that survived the whole window is not a part of a crash loop
+		now := time.Now()
+		if now.Sub(started) > s.limit.Window {
+			consecutive = 0
+		}
+		restarts = append(recent(restarts, now, s.limit.Window), now)
+		if s.limit.MaxRestarts > 0 && len(restarts) > s.limit.MaxRestarts {
+			s.logger.LogAttrs(ctx, slog.LevelError, "Restart rate exceeded, giving up",
+				slog.Int("max_restarts", s.limit.MaxRestarts), slog.Duration("window", s.limit.Window))
+			return fmt.Errorf("restart rate exceeded: %w", err)
+		}
+		consecutive++
+
+		delay := s.policy.Delay(consecutive)
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "State machine failed, restarting from Init",
+			slog.Int("restart", len(restarts)), slog.Duration("delay", delay), slog.String("error", err.Error()))
+		for _, o := range s.observers {
+			o.OnRestart(ctx, len(restarts), err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if s.reset != nil {
+			s.reset()
+		}
+	}
+}
+
+// recent drops restarts older than the window
+func recent(restarts []time.Time, now time.Time, window time.Duration) []time.Time {
+	kept := restarts[:0]
+	for _, at := range restarts {
+		if now.Sub(at) <= window {
+			kept = append(kept, at)
+		}
+	}
+	return kept
+}
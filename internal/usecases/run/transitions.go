@@ -0,0 +1,83 @@
+package run
+
+import (
+	"fmt"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// Terminal is the pseudo state before the first and after the last state of the machine
+const Terminal = "[*]"
+
+// Transition is an allowed edge of the state machine
+type Transition struct {
+	From        string
+	To          string
+	Description string
+}
+
+// Transitions is the table of allowed transitions, the README diagram is drawn from it
+var Transitions = []Transition{
+	{From: Terminal, To: states.Init, Description: "Application started"},
+	{From: states.Init, To: states.Attempter, Description: "Initialization successful, start attempting"},
+	{From: states.Init, To: states.Failover, Description: "Failure, ZooKeeper unavailable"},
+	{From: states.Init, To: states.Stopping, Description: "Received SIGTERM"},
+	{From: states.Init, To: states.Init, Description: "Watchdog restart"},
+	{From: states.Init, To: states.Maintenance, Description: "Maintenance requested"},
+	{From: states.Attempter, To: states.Leader, Description: "Own znode is the first in the election queue"},
+	{From: states.Attempter, To: states.Failover, Description: "Failure, ZooKeeper unavailable"},
+	{From: states.Attempter, To: states.Stopping, Description: "Received SIGTERM"},
+	{From: states.Attempter, To: states.Init, Description: "Watchdog restart"},
+	{From: states.Attempter, To: states.Attempter, Description: "Own znode disappeared, rejoining"},
+	{From: states.Attempter, To: states.Maintenance, Description: "Maintenance requested, candidacy withdrawn"},
+	{From: states.Leader, To: states.Attempter, Description: "Leader work keeps failing or ended, leadership released"},
+	{From: states.Leader, To: states.Failover, Description: "Failure, ZooKeeper unavailable"},
+	{From: states.Leader, To: states.Draining, Description: "Received SIGTERM"},
+	{From: states.Leader, To: states.Stopping, Description: "Fatal ZooKeeper error"},
+	{From: states.Leader, To: states.Init, Description: "Watchdog restart"},
+	{From: states.Leader, To: states.Maintenance, Description: "Maintenance requested, leadership released"},
+	{From: states.Maintenance, To: states.Attempter, Description: "Maintenance released"},
+	{From: states.Maintenance, To: states.Failover, Description: "Watchdog failover"},
+	{From: states.Maintenance, To: states.Init, Description: "Watchdog restart"},
+	{From: states.Maintenance, To: states.Stopping, Description: "Received SIGTERM"},
+	{From: states.Failover, To: states.Init, Description: "Connection to ZooKeeper recovered or watchdog restart"},
+	{From: states.Failover, To: states.Stopping, Description: "Received SIGTERM or recovery attempts exhausted"},
+	{From: states.Draining, To: states.Stopping, Description: "In-flight work finished, leadership released"},
+	{From: states.Stopping, To: Terminal, Description: "Resources released"},
+}
+
+// TransitionError is returned by the runner when a state moves to a target missing in the transition table
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid transition from %s to %s", e.From, e.To)
+}
+
+// allowed reports whether the transition table has an edge between the states
+func allowed(from, to string) bool {
+	for _, t := range Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition validates the edge between the states, nil states stand for the terminal pseudo state
+func checkTransition(from, to states.AutomataState) error {
+	fromName, toName := nameOf(from), nameOf(to)
+	if !allowed(fromName, toName) {
+		return &TransitionError{From: fromName, To: toName}
+	}
+	return nil
+}
+
+func nameOf(state states.AutomataState) string {
+	if state == nil {
+		return Terminal
+	}
+	return state.String()
+}
@@ -0,0 +1,205 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// ErrWatchdog is the cause of the state context cancelled by the watchdog
+var ErrWatchdog = errors.New("state exceeded watchdog limit")
+
+// WatchdogAction is what the runner does with a state exceeding its limits
+type WatchdogAction string
+
+const (
+	// WatchdogLog only logs the exceeded limit
+	WatchdogLog WatchdogAction = "log"
+	// WatchdogFailover interrupts the state and moves to Failover
+	WatchdogFailover WatchdogAction = "failover"
+	// WatchdogRestart interrupts the state and starts the machine over from Init
+	WatchdogRestart WatchdogAction = "restart"
+)
+
+// ParseWatchdogAction validates the action name
+func ParseWatchdogAction(value string) (WatchdogAction, error) {
+	switch action := WatchdogAction(value); action {
+	case WatchdogLog, WatchdogFailover, WatchdogRestart:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown watchdog action %q, expected log, failover or restart", value)
+	}
+}
+
+// Limits bounds the time spent in a state. Zero values disable the limit
+type Limits struct {
+	// MaxDuration is the longest time the state may run
+	MaxDuration time.Duration
+	// StallTimeout is the longest time the state may run without reporting progress
+	StallTimeout time.Duration
+}
+
+// Watchdog holds the limits of states and the action applied when one is exceeded
+type Watchdog struct {
+	Limits map[string]Limits
+	Action WatchdogAction
+}
+
+// ParseWatchdog builds the watchdog from "State=duration" lists of maximum durations and stall timeouts
+func ParseWatchdog(maxDuration, stallTimeout []string, action string) (Watchdog, error) {
+	w := Watchdog{Limits: make(map[string]Limits)}
+	var err error
+	w.Action, err = ParseWatchdogAction(action)
+	if err != nil {
+		return Watchdog{}, err
+	}
+	err = parseLimits(maxDuration, func(state string, d time.Duration) {
+		limits := w.Limits[state]
+		limits.MaxDuration = d
+		w.Limits[state] = limits
+	})
+	if err != nil {
+		return Watchdog{}, fmt.Errorf("max duration: %w", err)
+	}
+	err = parseLimits(stallTimeout, func(state string, d time.Duration) {
+		limits := w.Limits[state]
+		limits.StallTimeout = d
+		w.Limits[state] = limits
+	})
+	if err != nil {
+		return Watchdog{}, fmt.Errorf("stall timeout: %w", err)
+	}
+	return w, nil
+}
+
+func parseLimits(values []string, set func(state string, d time.Duration)) error {
+	for _, value := range values {
+		state, raw, found := strings.Cut(value, "=")
+		if !found {
+			return fmt.Errorf("limit %q is not in State=duration form", value)
+		}
+		if !known(state) {
+			return fmt.Errorf("unknown state %q", state)
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("limit of state %s: %w", state, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("limit of state %s must be positive", state)
+		}
+		set(state, d)
+	}
+	return nil
+}
+
+// known reports whether the state is a part of the transition table
+func known(state string) bool {
+	for _, t := range Transitions {
+		if state != Terminal && (t.From == state || t.To == state) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch guards the running state with its limits. The returned context carries the progress reporter,
+// the returned function stops the watch and reports whether the state was interrupted
+func (r *LoopRunner) watch(ctx context.Context, state string) (context.Context, func() bool) {
+	limits := r.watchdog.Limits[state]
+	if limits == (Limits{}) {
+		return ctx, func() bool { return false }
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	progress := make(chan struct{}, 1)
+	ctx = states.WithProgress(ctx, func() {
+		select {
+		case progress <- struct{}{}:
+		default:
+		}
+	})
+
+	var fired atomic.Bool
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		deadline := timerFor(limits.MaxDuration)
+		stall := timerFor(limits.StallTimeout)
+		defer stopTimer(deadline)
+		defer stopTimer(stall)
+
+		for {
+			var reason string
+			select {
+			case <-done:
+				return
+			case <-progress:
+				if stall != nil {
+					stall.Reset(limits.StallTimeout)
+				}
+				continue
+			case <-channelOf(deadline):
+				reason = "max duration exceeded"
+			case <-channelOf(stall):
+				reason = "no progress reported"
+			}
+
+			// The shutdown states have nowhere to go, they are only reported
+			if r.watchdog.Action == WatchdogLog || state == states.Draining || state == states.Stopping {
+				r.logger.LogAttrs(ctx, slog.LevelWarn, "State exceeded watchdog limit", slog.String("state", state),
+					slog.String("reason", reason), slog.Any("limits", limits))
+				continue
+			}
+			r.logger.LogAttrs(ctx, slog.LevelError, "State exceeded watchdog limit, interrupting", slog.String("state", state),
+				slog.String("reason", reason), slog.String("action", string(r.watchdog.Action)))
+			fired.Store(true)
+			cancel(fmt.Errorf("%w: %s %s", ErrWatchdog, state, reason))
+			return
+		}
+	}()
+
+	return ctx, func() bool {
+		close(done)
+		<-stopped
+		cancel(nil)
+		return fired.Load()
+	}
+}
+
+// recoverState picks the state the watchdog moves an interrupted state to
+func (r *LoopRunner) recoverState(state states.AutomataState) (states.AutomataState, error) {
+	// Failover interrupted by its own watchdog can only start over
+	if r.watchdog.Action == WatchdogFailover && state.String() != states.Failover {
+		return r.factory.GetFailoverState()
+	}
+	return r.factory.GetInitState()
+}
+
+func timerFor(d time.Duration) *time.Timer {
+	if d <= 0 {
+		return nil
+	}
+	return time.NewTimer(d)
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// channelOf returns the channel of the timer, a nil timer never fires
+func channelOf(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
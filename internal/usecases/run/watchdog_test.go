@@ -0,0 +1,210 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/usecases/run/states"
+)
+
+// blocking returns a state that waits for the cancellation of its context and moves to the given state
+func blocking(name string, to states.AutomataState, cause *error) *scripted {
+	return &scripted{name: name, run: func(ctx context.Context) (states.AutomataState, error) {
+		<-ctx.Done()
+		if cause != nil {
+			*cause = context.Cause(ctx)
+		}
+		return to, nil
+	}}
+}
+
+func TestRecoverState(t *testing.T) {
+	tests := []struct {
+		action WatchdogAction
+		state  string
+		want   string
+	}{
+		{action: WatchdogFailover, state: states.Leader, want: states.Failover},
+		{action: WatchdogFailover, state: states.Attempter, want: states.Failover},
+		{action: WatchdogFailover, state: states.Failover, want: states.Init},
+		{action: WatchdogRestart, state: states.Leader, want: states.Init},
+		{action: WatchdogRestart, state: states.Failover, want: states.Init},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action)+" "+tt.state, func(t *testing.T) {
+			f := newFakeFactory(&scripted{name: states.Init}, &scripted{name: states.Failover})
+			r, _ := newTestRunner(f)
+			r.SetWatchdog(Watchdog{Action: tt.action})
+
+			got, err := r.recoverState(&scripted{name: tt.state})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("recoverState = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchdogInterruptsState(t *testing.T) {
+	var cause error
+	stopping := &scripted{name: states.Stopping}
+	// The choice of the interrupted state is discarded, the factory provides the next one
+	first := blocking(states.Init, &scripted{name: states.Attempter}, &cause)
+	r, rec := newTestRunner(newFakeFactory(next(states.Init, stopping)))
+	r.SetWatchdog(Watchdog{
+		Limits: map[string]Limits{states.Init: {MaxDuration: 10 * time.Millisecond}},
+		Action: WatchdogRestart,
+	})
+
+	if err := r.Run(context.Background(), first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !errors.Is(cause, ErrWatchdog) {
+		t.Errorf("cause of the cancellation = %v, want %v", cause, ErrWatchdog)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Init, ReasonWatchdog},
+		event{states.Init, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestWatchdogOnlyLogsShutdownStates(t *testing.T) {
+	const limit = 10 * time.Millisecond
+	stopping := &scripted{name: states.Stopping, run: func(ctx context.Context) (states.AutomataState, error) {
+		time.Sleep(5 * limit)
+		if ctx.Err() != nil {
+			t.Errorf("Stopping was interrupted: %v", context.Cause(ctx))
+		}
+		return nil, nil //nolint:nilnil // nil state ends the machine
+	}}
+	draining := &scripted{name: states.Draining, run: func(ctx context.Context) (states.AutomataState, error) {
+		time.Sleep(5 * limit)
+		if ctx.Err() != nil {
+			t.Errorf("Draining was interrupted: %v", context.Cause(ctx))
+		}
+		return stopping, nil
+	}}
+	first := next(states.Init, next(states.Attempter, next(states.Leader, draining)))
+	r, rec := newTestRunner(newFakeFactory())
+	r.SetWatchdog(Watchdog{
+		Limits: map[string]Limits{
+			states.Draining: {MaxDuration: limit, StallTimeout: limit},
+			states.Stopping: {MaxDuration: limit, StallTimeout: limit},
+		},
+		Action: WatchdogFailover,
+	})
+
+	if err := r.Run(context.Background(), first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Attempter, ReasonCompleted},
+		event{states.Attempter, states.Leader, ReasonCompleted},
+		event{states.Leader, states.Draining, ReasonCompleted},
+		event{states.Draining, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestWatchdogStallReset(t *testing.T) {
+	const stall = 100 * time.Millisecond
+	stopping := &scripted{name: states.Stopping}
+	// Progress is reported well within the stall timeout for three times as long as the timeout
+	first := &scripted{name: states.Init, run: func(ctx context.Context) (states.AutomataState, error) {
+		ticker := time.NewTicker(stall / 10)
+		defer ticker.Stop()
+		deadline := time.After(3 * stall)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil, context.Cause(ctx)
+			case <-deadline:
+				return stopping, nil
+			case <-ticker.C:
+				states.ReportProgress(ctx)
+			}
+		}
+	}}
+	r, rec := newTestRunner(newFakeFactory())
+	r.SetWatchdog(Watchdog{
+		Limits: map[string]Limits{states.Init: {StallTimeout: stall}},
+		Action: WatchdogRestart,
+	})
+
+	if err := r.Run(context.Background(), first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestWatchdogStall(t *testing.T) {
+	var cause error
+	stopping := &scripted{name: states.Stopping}
+	first := blocking(states.Init, &scripted{name: states.Attempter}, &cause)
+	r, rec := newTestRunner(newFakeFactory(next(states.Failover, next(states.Init, stopping))))
+	r.SetWatchdog(Watchdog{
+		Limits: map[string]Limits{states.Init: {StallTimeout: 10 * time.Millisecond}},
+		Action: WatchdogFailover,
+	})
+
+	if err := r.Run(context.Background(), first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !errors.Is(cause, ErrWatchdog) || !strings.Contains(cause.Error(), "no progress reported") {
+		t.Errorf("cause of the cancellation = %v, want a stall", cause)
+	}
+	rec.expect(t,
+		event{Terminal, states.Init, ReasonStarted},
+		event{states.Init, states.Failover, ReasonWatchdog},
+		event{states.Failover, states.Init, ReasonCompleted},
+		event{states.Init, states.Stopping, ReasonCompleted},
+		event{states.Stopping, Terminal, ReasonCompleted},
+	)
+}
+
+func TestParseWatchdog(t *testing.T) {
+	w, err := ParseWatchdog([]string{"Leader=1m"}, []string{"Leader=10s", "Attempter=5s"}, "failover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Limits{
+		states.Leader:    {MaxDuration: time.Minute, StallTimeout: 10 * time.Second},
+		states.Attempter: {StallTimeout: 5 * time.Second},
+	}
+	if w.Action != WatchdogFailover || len(w.Limits) != len(want) {
+		t.Fatalf("ParseWatchdog = %+v, want %v", w, want)
+	}
+	for state, limits := range want {
+		if w.Limits[state] != limits {
+			t.Errorf("limits of %s = %+v, want %+v", state, w.Limits[state], limits)
+		}
+	}
+
+	for _, tt := range []struct {
+		maxDuration []string
+		action      string
+	}{
+		{maxDuration: []string{"Leader"}, action: "log"},
+		{maxDuration: []string{"Unknown=1m"}, action: "log"},
+		{maxDuration: []string{"Leader=soon"}, action: "log"},
+		{maxDuration: []string{"Leader=0s"}, action: "log"},
+		{action: "panic"},
+	} {
+		if _, err := ParseWatchdog(tt.maxDuration, nil, tt.action); err == nil {
+			t.Errorf("ParseWatchdog(%v, %s) succeeded", tt.maxDuration, tt.action)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package zkconn
+
+import (
+	"errors"
+
+	"github.com/central-university-dev/2024-spring-go-course-lesson8-leader-election/internal/termination"
+	"github.com/go-zookeeper/zk"
+)
+
+// Action is what a state does with a ZooKeeper error
+type Action int
+
+const (
+	// ActionFailover replaces the connection, the error is expected to go away
+	ActionFailover Action = iota
+	// ActionStop ends the node, retrying cannot help
+	ActionStop
+	// ActionHandle means the error is an expected outcome that the caller handles in place
+	ActionHandle
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionStop:
+		return "stop"
+	case ActionHandle:
+		return "handle"
+	default:
+		return "failover"
+	}
+}
+
+// Classify maps the ZooKeeper error to the action. Unknown errors go to Failover
+func Classify(err error) Action {
+	switch {
+	case errors.Is(err, zk.ErrNodeExists):
+		return ActionHandle
+	case errors.Is(err, zk.ErrNoAuth), errors.Is(err, zk.ErrAuthFailed), errors.Is(err, zk.ErrInvalidACL),
+		errors.Is(err, zk.ErrBadArguments), errors.Is(err, zk.ErrInvalidPath), errors.Is(err, ErrClosed):
+		return ActionStop
+	default:
+		return ActionFailover
+	}
+}
+
+// TerminationReason tells why an error classified as ActionStop ends the node
+func TerminationReason(err error) termination.Reason {
+	switch {
+	case errors.Is(err, zk.ErrNoAuth), errors.Is(err, zk.ErrAuthFailed), errors.Is(err, zk.ErrInvalidACL):
+		return termination.Auth
+	case errors.Is(err, zk.ErrBadArguments), errors.Is(err, zk.ErrInvalidPath):
+		return termination.Configuration
+	default:
+		return termination.Unexpected
+	}
+}
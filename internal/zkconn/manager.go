@@ -0,0 +1,227 @@
+package zkconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// SessionTimeout is the ZooKeeper session timeout, it also bounds the wait for a new session
+const SessionTimeout = 10 * time.Second
+
+// ErrClosed is returned after the manager was closed
+var ErrClosed = errors.New("zookeeper connection manager is closed")
+
+// Manager owns the ZooKeeper client. States borrow the current connection from it,
+// only the manager dials, replaces and closes connections
+type Manager struct {
+	logger  *slog.Logger
+	servers []string
+
+	mu     sync.Mutex
+	conn   *zk.Conn
+	closed bool
+}
+
+func New(logger *slog.Logger, servers []string) *Manager {
+	return &Manager{
+		logger:  logger.With("subsystem", "ZKConnManager"),
+		servers: servers,
+	}
+}
+
+// Conn returns the current connection, dialing a new one if there is none yet
+func (m *Manager) Conn(ctx context.Context) (*zk.Conn, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	conn := m.conn
+	m.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	return m.dial(ctx)
+}
+
+// Reconnect closes the current connection and dials a new one
+func (m *Manager) Reconnect(ctx context.Context) (*zk.Conn, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	m.drop()
+	m.mu.Unlock()
+	return m.dial(ctx)
+}
+
+// Current returns the current connection without dialing, nil if there is none
+func (m *Manager) Current() *zk.Conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn
+}
+
+// Healthy reports whether the current connection has a live session
+func (m *Manager) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conn != nil && m.conn.State() == zk.StateHasSession
+}
+
+// Close closes the current connection, later calls do nothing
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	m.drop()
+	m.logger.Info("Connection manager closed")
+}
+
+func (m *Manager) drop() {
+	if m.conn == nil {
+		return
+	}
+	m.conn.Close()
+	m.conn = nil
+}
+
+// dial connects without holding the lock, so readers of the current connection are not blocked
+// for the session timeout, and installs the connection afterwards. A connection dialed concurrently
+// by another caller wins, the one dialed here is closed then
+func (m *Manager) dial(ctx context.Context) (*zk.Conn, error) {
+	conn, events, err := m.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		conn.Close()
+		go drain(events)
+		return nil, ErrClosed
+	}
+	if m.conn != nil {
+		conn.Close()
+		go drain(events)
+		return m.conn, nil
+	}
+	m.conn = conn
+	go m.watch(events)
+	return conn, nil
+}
+
+// connect connects and waits for the session, the connection is closed if the session is not established in time
+func (m *Manager) connect(ctx context.Context) (*zk.Conn, <-chan zk.Event, error) {
+	conn, events, err := zk.Connect(m.servers, SessionTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SessionTimeout)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			go drain(events)
+			return nil, nil, fmt.Errorf("establish zookeeper session: %w", ctx.Err())
+		case event, ok := <-events:
+			if !ok {
+				return nil, nil, fmt.Errorf("establish zookeeper session: connection closed")
+			}
+			if event.State != zk.StateHasSession {
+				continue
+			}
+			m.logger.LogAttrs(ctx, slog.LevelInfo, "Zookeeper session established",
+				slog.String("server", conn.Server()), slog.Int64("session", conn.SessionID()))
+			return conn, events, nil
+		}
+	}
+}
+
+// watch logs the session events of the connection until it is closed
+func (m *Manager) watch(events <-chan zk.Event) {
+	for event := range events {
+		if event.Type != zk.EventSession {
+			continue
+		}
+		m.logger.Info("Zookeeper session event", slog.String("state", event.State.String()), slog.String("server", event.Server))
+	}
+}
+
+func drain(events <-chan zk.Event) {
+	for range events {
+	}
+}
+
+// Probe checks the current connection with a round trip to the server
+func (m *Manager) Probe(ctx context.Context) error {
+	conn := m.Current()
+	if conn == nil {
+		return errors.New("zookeeper connection not established")
+	}
+	if state := conn.State(); state != zk.StateHasSession {
+		return fmt.Errorf("zookeeper session is not established: %s", state)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SessionTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.Exists("/")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("probe zookeeper: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("probe zookeeper: %w", ctx.Err())
+	}
+}
+
+// Delete deletes the znode of any version and waits until the server acknowledges it or the context is done
+func (m *Manager) Delete(ctx context.Context, path string) error {
+	conn := m.Current()
+	if conn == nil {
+		return errors.New("zookeeper connection not established")
+	}
+	if state := conn.State(); state != zk.StateHasSession {
+		return fmt.Errorf("zookeeper session is not established: %s", state)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.Delete(path, -1)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", path, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("delete %s: %w", path, ctx.Err())
+	}
+}
+
+// Reopen allows dialing again after Close, it is used when the state machine restarts in the same process
+func (m *Manager) Reopen() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = false
+}